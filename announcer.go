@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Chain events a guild can have announced.
+const (
+	announceNewConnection = "new_connection"
+	announceEOL           = "eol"
+	announceCriticalMass  = "critical_mass"
+	announceCollapsed     = "collapsed"
+)
+
+// maxEmbedsPerMessage is Discord's limit on embeds in one webhook message.
+const maxEmbedsPerMessage = 10
+
+// AnnounceConfig is a guild's chain announcement settings.
+type AnnounceConfig struct {
+	HomeID     int             `json:"home_id"`
+	HomeName   string          `json:"home_name"`
+	ChannelID  string          `json:"channel_id,omitempty"` // channel the webhook posts to; empty for the default
+	WebhookURL string          `json:"webhook_url"`          // empty means the bot's default alert webhook
+	Events     map[string]bool `json:"events"`
+}
+
+// Announcer posts wormhole chain changes from the Tripwire feed to each
+// guild's alert channel through Discord webhooks.
+type Announcer struct {
+	esiClient      *ESIClient
+	graphStore     *GraphStore
+	store          *Store
+	defaultWebhook string
+	httpClient     *http.Client
+
+	mu        sync.Mutex
+	previous  *TripwireData        // the latest snapshot, diffed against on the next poll
+	lastAlert map[string]time.Time // guild ID + system ID -> last hostile alert
+}
+
+// NewAnnouncer creates an announcer. initial is the snapshot loaded at
+// startup, so that the first poll can tell which holes were in the chain.
+func NewAnnouncer(esiClient *ESIClient, graphStore *GraphStore, store *Store, defaultWebhook string, initial *TripwireData) *Announcer {
+	return &Announcer{
+		esiClient:      esiClient,
+		graphStore:     graphStore,
+		store:          store,
+		defaultWebhook: defaultWebhook,
+		httpClient:     &http.Client{Timeout: 15 * time.Second},
+		previous:       initial,
+		lastAlert:      make(map[string]time.Time),
+	}
+}
+
+// HasDefaultWebhook reports whether DISCORD_WEB_HOOK is configured.
+func (a *Announcer) HasDefaultWebhook() bool {
+	return a.defaultWebhook != ""
+}
+
+// HandleTripwire is the TripwireFeed subscriber. Posting happens in the
+// background so the feed is not held up by Discord.
+func (a *Announcer) HandleTripwire(data *TripwireData, diff TripwireDiff) {
+	a.mu.Lock()
+	before := a.previous
+	a.previous = data
+	a.mu.Unlock()
+
+	if len(diff.Changes) == 0 {
+		return
+	}
+	configs, err := a.store.AnnounceConfigs()
+	if err != nil {
+		log.Printf("[ANNOUNCER] ERROR: could not load announcement settings: %v", err)
+		return
+	}
+
+	go func() {
+		for guildID, cfg := range configs {
+			embeds := a.chainEmbeds(cfg, before, data, diff)
+			if len(embeds) == 0 {
+				continue
+			}
+			if err := a.Post(a.webhookFor(cfg), embeds); err != nil {
+				log.Printf("[ANNOUNCER] ERROR: posting to guild %s: %v", guildID, err)
+			}
+		}
+	}()
+}
+
+func (a *Announcer) webhookFor(cfg AnnounceConfig) string {
+	if cfg.WebhookURL != "" {
+		return cfg.WebhookURL
+	}
+	return a.defaultWebhook
+}
+
+// chainEmbeds builds one embed per change a guild wants to hear about.
+// Removed holes are checked against the chain as it was before they went.
+func (a *Announcer) chainEmbeds(cfg AnnounceConfig, before, after *TripwireData, diff TripwireDiff) []*discordgo.MessageEmbed {
+	chainBefore := tripwireChain(before, cfg.HomeID)
+	chainAfter := tripwireChain(after, cfg.HomeID)
+
+	var embeds []*discordgo.MessageEmbed
+	for _, change := range diff.Changes {
+		if change.Wormhole == nil {
+			continue
+		}
+		wh := *change.Wormhole
+
+		switch change.Kind {
+		case WormholeAdded:
+			from, to, ok := tripwireEnds(after, wh)
+			if !ok || !cfg.Events[announceNewConnection] {
+				continue
+			}
+			jumps, inChain := nearestInChain(chainAfter, from, to)
+			if !inChain {
+				continue
+			}
+			embeds = append(embeds, a.chainEmbed(cfg, "🆕 New Connection", 0x2196F3, after, wh,
+				fmt.Sprintf("Mapped %s from %s.", plural(jumps, "jump"), cfg.HomeName)))
+
+		case WormholeUpdated:
+			from, to, ok := tripwireEnds(after, wh)
+			if !ok {
+				continue
+			}
+			if _, inChain := nearestInChain(chainAfter, from, to); !inChain {
+				continue
+			}
+			prev := change.PreviousWormhole
+			if cfg.Events[announceEOL] && wh.Life == "critical" && prev.Life != "critical" {
+				embeds = append(embeds, a.chainEmbed(cfg, "⏳ Wormhole End of Life", 0xFFC107, after, wh, "Less than 4 hours left."))
+			}
+			if cfg.Events[announceCriticalMass] && wh.Mass == "critical" && prev.Mass != "critical" {
+				embeds = append(embeds, a.chainEmbed(cfg, "⚠️ Wormhole Critical Mass", 0xFF9800, after, wh, "Less than 10% of its mass left."))
+			}
+
+		case WormholeRemoved:
+			from, to, ok := tripwireEnds(before, wh)
+			if !ok || !cfg.Events[announceCollapsed] {
+				continue
+			}
+			if _, inChain := nearestInChain(chainBefore, from, to); !inChain {
+				continue
+			}
+			embeds = append(embeds, a.chainEmbed(cfg, "💥 Connection Gone", 0xF44336, before, wh, "Removed from the chain."))
+		}
+	}
+	return embeds
+}
+
+// chainEmbed describes one wormhole: both ends with their signatures, type, life and mass.
+func (a *Announcer) chainEmbed(cfg AnnounceConfig, title string, color int, data *TripwireData, wh TripwireWormhole, detail string) *discordgo.MessageEmbed {
+	from, to, _ := tripwireEnds(data, wh)
+	whType := strings.ToUpper(wh.Type)
+	if whType == "" || whType == "????" {
+		whType = "Unknown"
+	}
+	return &discordgo.MessageEmbed{
+		Author:      embedAuthor,
+		Title:       title,
+		Description: fmt.Sprintf("**%s** (%s) ⇄ **%s** (%s)\n%s", a.systemName(from), signatureLabel(data, wh.InitialID), a.systemName(to), signatureLabel(data, wh.SecondaryID), detail),
+		Color:       color,
+		Timestamp:   time.Now().Format(time.RFC3339),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Type", Value: whType, Inline: true},
+			{Name: "Life", Value: wh.Life, Inline: true},
+			{Name: "Mass", Value: wh.Mass, Inline: true},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: "Home: " + cfg.HomeName},
+	}
+}
+
+func (a *Announcer) systemName(systemID int) string {
+	if sysInfo, err := a.esiClient.GetSystemDetails(systemID); err == nil {
+		return sysInfo.Name
+	}
+	return fmt.Sprintf("Unknown (%d)", systemID)
+}
+
+// Post sends embeds to a Discord webhook, splitting them across messages as needed.
+func (a *Announcer) Post(webhookURL string, embeds []*discordgo.MessageEmbed) error {
+	if webhookURL == "" {
+		return fmt.Errorf("no webhook configured")
+	}
+	for start := 0; start < len(embeds); start += maxEmbedsPerMessage {
+		end := min(start+maxEmbedsPerMessage, len(embeds))
+		payload, err := json.Marshal(map[string]interface{}{"embeds": embeds[start:end]})
+		if err != nil {
+			return fmt.Errorf("failed to encode webhook message: %w", err)
+		}
+		resp, err := a.httpClient.Post(webhookURL, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("webhook request failed: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("webhook returned status: %d", resp.StatusCode)
+		}
+	}
+	return nil
+}
+
+// tripwireEnds returns the systems at both ends of a Tripwire wormhole.
+func tripwireEnds(data *TripwireData, wh TripwireWormhole) (int, int, bool) {
+	if data == nil {
+		return 0, 0, false
+	}
+	sigA, okA := data.Signatures[wh.InitialID]
+	sigB, okB := data.Signatures[wh.SecondaryID]
+	if !okA || !okB {
+		return 0, 0, false
+	}
+	from, _ := strconv.Atoi(sigA.SystemID)
+	to, _ := strconv.Atoi(sigB.SystemID)
+	return from, to, from != 0 && to != 0
+}
+
+// tripwireChain returns every system reachable from home through the mapped
+// wormholes, with the number of wormhole jumps it takes to get there.
+func tripwireChain(data *TripwireData, homeID int) map[int]int {
+	chain := map[int]int{homeID: 0}
+	if data == nil {
+		return chain
+	}
+	links := make(map[int][]int)
+	for _, wh := range data.Wormholes {
+		if from, to, ok := tripwireEnds(data, wh); ok {
+			links[from] = append(links[from], to)
+			links[to] = append(links[to], from)
+		}
+	}
+	queue := []int{homeID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range links[current] {
+			if _, seen := chain[next]; !seen {
+				chain[next] = chain[current] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return chain
+}
+
+// nearestInChain returns how far the nearer end of a hole is from home, if either end is in the chain.
+func nearestInChain(chain map[int]int, from, to int) (int, bool) {
+	jumpsFrom, okFrom := chain[from]
+	jumpsTo, okTo := chain[to]
+	switch {
+	case okFrom && okTo:
+		return min(jumpsFrom, jumpsTo), true
+	case okFrom:
+		return jumpsFrom, true
+	case okTo:
+		return jumpsTo, true
+	}
+	return 0, false
+}
+
+// signatureLabel returns a signature's in-game ID, or "???" if it is not scanned yet.
+func signatureLabel(data *TripwireData, sigKey string) string {
+	if sig, ok := data.Signatures[sigKey]; ok && sig.SignatureID != nil && *sig.SignatureID != "" {
+		return strings.ToUpper(*sig.SignatureID)
+	}
+	return "???"
+}
+
+// plural formats a count with a noun, adding an s when needed.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
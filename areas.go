@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Area is a group of systems that can be excluded by name: a region, a
+// constellation or a security band.
+type Area struct {
+	Kind string // "region", "constellation" or "security"
+	ID   int    // region or constellation ID; unused for security bands
+	Name string
+}
+
+// securityBands maps the accepted spellings of each security band to its display name.
+var securityBands = map[string]string{
+	"highsec": "High-sec", "hisec": "High-sec", "high-sec": "High-sec", "hs": "High-sec",
+	"lowsec": "Low-sec", "low-sec": "Low-sec", "ls": "Low-sec",
+	"nullsec": "Null-sec", "null-sec": "Null-sec", "null": "Null-sec", "ns": "Null-sec",
+	"wormhole": "Wormhole space", "wormholes": "Wormhole space", "w-space": "Wormhole space", "jspace": "Wormhole space",
+}
+
+// AreaResolver turns region, constellation and security band names into the systems they contain.
+// Region and constellation names are looked up through ESI once and remembered.
+type AreaResolver struct {
+	locations map[int]SystemLocation
+	systems   *SystemIndex
+
+	mu    sync.Mutex
+	cache map[string]*Area // lower-case name -> area; nil when the name is not an area
+}
+
+func NewAreaResolver(locations map[int]SystemLocation, systems *SystemIndex) *AreaResolver {
+	return &AreaResolver{
+		locations: locations,
+		systems:   systems,
+		cache:     make(map[string]*Area),
+	}
+}
+
+// Resolve returns the area called name, or nil if it is not a known area.
+func (r *AreaResolver) Resolve(name string) (*Area, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if band, ok := securityBands[key]; ok {
+		return &Area{Kind: "security", Name: band}, nil
+	}
+
+	r.mu.Lock()
+	area, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok {
+		return area, nil
+	}
+
+	var ids struct {
+		Regions []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"regions"`
+		Constellations []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"constellations"`
+	}
+	if err := esiPostJSON("/universe/ids/", []string{strings.TrimSpace(name)}, &ids); err != nil {
+		return nil, fmt.Errorf("failed to look up %q: %w", name, err)
+	}
+	switch {
+	case len(ids.Regions) > 0:
+		area = &Area{Kind: "region", ID: ids.Regions[0].ID, Name: ids.Regions[0].Name}
+	case len(ids.Constellations) > 0:
+		area = &Area{Kind: "constellation", ID: ids.Constellations[0].ID, Name: ids.Constellations[0].Name}
+	}
+
+	r.mu.Lock()
+	r.cache[key] = area
+	r.mu.Unlock()
+	return area, nil
+}
+
+// Systems returns every system in an area.
+func (r *AreaResolver) Systems(area *Area) []int {
+	var ids []int
+	switch area.Kind {
+	case "region", "constellation":
+		for id, loc := range r.locations {
+			if (area.Kind == "region" && loc.RegionID == area.ID) || (area.Kind == "constellation" && loc.ConstellationID == area.ID) {
+				ids = append(ids, id)
+			}
+		}
+	case "security":
+		for _, sys := range r.systems.entries {
+			if inSecurityBand(sys, area.Name) {
+				ids = append(ids, sys.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// inSecurityBand reports whether a system belongs to a band, using EVE's
+// rounding: anything above 0.0 and below 0.05 still counts as low-sec.
+func inSecurityBand(sys systemIndexEntry, band string) bool {
+	wormhole := sys.ID >= 31000000 && sys.ID < 32000000
+	switch band {
+	case "Wormhole space":
+		return wormhole
+	case "High-sec":
+		return !wormhole && sys.Security >= 0.45
+	case "Low-sec":
+		return !wormhole && sys.Security > 0 && sys.Security < 0.45
+	case "Null-sec":
+		return !wormhole && sys.Security <= 0
+	}
+	return false
+}
@@ -1,31 +1,113 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 type Service struct {
-	token         string
-	universeGraph map[int][]int
-	graphMutex    *sync.RWMutex
-	esiClient     *ESIClient
+	token        string
+	graphStore   *GraphStore
+	esiClient    *ESIClient
+	routes       *routeCache
+	store        *Store
+	sso          *SSOClient // nil when SSO is not configured
+	defaultAvoid []string   // always-avoid entries for guilds that have not set their own
+	systems      *SystemIndex
+	areas        *AreaResolver
+	tripwire     *TripwireFeed
+	announcer    *Announcer
+	killFeed     *KillFeed // nil when the zKillboard feed is not configured
+	killData     *KillDataUpdater
+	positions    *SystemPositions
+	locations    map[int]SystemLocation
 }
 
-func NewService(token string, graph map[int][]int, mutex *sync.RWMutex, esi *ESIClient) *Service {
+// maxRouteAlternatives is how many routes /route offers in its selector.
+const maxRouteAlternatives = 3
+
+// Systems and regions with special routing rules.
+const (
+	zarzakhSystemID = 30100000
+	pochvenRegionID = 10000070
+)
+
+// maxTourStops caps how many systems /tour will order.
+const maxTourStops = 25
+
+// maxTripWaypoints caps how many via systems /trip will route through.
+const maxTripWaypoints = 25
+
+// jdcMinLevel is the lowest Jump Drive Calibration level /jumproute accepts.
+var jdcMinLevel = 0.0
+
+// routeSelectPrefix prefixes the CustomID of the alternative-route menu; the
+// route ID and compact flag follow.
+const routeSelectPrefix = "route_alt:"
+
+// maxEmbedFieldLength is Discord's limit on the value of one embed field.
+const maxEmbedFieldLength = 1024
+
+// compactMinRun is the shortest run of high-sec systems compact mode collapses.
+const compactMinRun = 3
+
+// routePagePrefix prefixes the CustomID of the Previous/Next and compact
+// buttons; the route ID and the view to show follow.
+const routePagePrefix = "route_page:"
+
+// routeRerunPrefix prefixes the CustomID of the Re-run button; the route ID follows.
+// The /history menu uses the bare prefix and carries the route ID in its value.
+const routeRerunPrefix = "route_rerun:"
+
+// setDestinationPrefix prefixes the CustomID of the Set Destination button; the
+// route ID and alternative follow.
+const setDestinationPrefix = "route_dest:"
+
+// maxAutocompleteChoices is Discord's limit on autocomplete suggestions.
+const maxAutocompleteChoices = 25
+
+// listOptions are the options holding comma-separated system lists; autocomplete
+// completes their last entry.
+var listOptions = map[string]bool{"exclude": true, "via": true, "systems": true}
+
+// routeHistoryLimit is how many past routes /history lists.
+const routeHistoryLimit = 10
+
+// routeHistoryKeep is how many routes are kept per user in each server. Older
+// ones are deleted as new ones are saved, and can no longer be re-run.
+const routeHistoryKeep = 50
+
+var embedAuthor = &discordgo.MessageEmbedAuthor{
+	Name:    "Short Circuit Bot",
+	IconURL: "https://images.evetech.net/corporations/98330748/logo?size=64",
+}
+
+func NewService(token string, graphStore *GraphStore, esi *ESIClient, tripwire *TripwireFeed, announcer *Announcer, killFeed *KillFeed, killData *KillDataUpdater, store *Store, sso *SSOClient, defaultAvoid []string, systems *SystemIndex, positions *SystemPositions, locations map[int]SystemLocation) *Service {
 	return &Service{
-		token:         token,
-		universeGraph: graph,
-		graphMutex:    mutex,
-		esiClient:     esi,
+		token:        token,
+		graphStore:   graphStore,
+		esiClient:    esi,
+		routes:       newRouteCache(),
+		store:        store,
+		sso:          sso,
+		defaultAvoid: defaultAvoid,
+		systems:      systems,
+		areas:        NewAreaResolver(locations, systems),
+		tripwire:     tripwire,
+		announcer:    announcer,
+		killFeed:     killFeed,
+		killData:     killData,
+		positions:    positions,
+		locations:    locations,
 	}
 }
 
@@ -59,209 +141,1802 @@ func (s *Service) ready(sess *discordgo.Session, event *discordgo.Ready) {
 		{
 			Name:        "route",
 			Description: "Calculates the shortest route between two solar systems.",
+			Options: append([]*discordgo.ApplicationCommandOption{
+				// Required options must come first, so end is listed before start.
+				{Type: discordgo.ApplicationCommandOptionString, Name: "end", Description: "The destination solar system.", Required: true, Autocomplete: true},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "start", Description: "The starting solar system. Defaults to your linked character's location.", Required: false, Autocomplete: true},
+			}, routeFilterOptions()...),
+		},
+		{
+			Name:        "trip",
+			Description: "Plans a multi-stop route visiting waypoints in the given order.",
+			Options: append([]*discordgo.ApplicationCommandOption{
+				{Type: discordgo.ApplicationCommandOptionString, Name: "start", Description: "The starting solar system.", Required: true, Autocomplete: true},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "via", Description: "Comma-separated waypoints, visited in order.", Required: true, Autocomplete: true},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "end", Description: "The final destination.", Required: true, Autocomplete: true},
+			}, routeFilterOptions()...),
+		},
+		{
+			Name:        "tour",
+			Description: "Finds the shortest order to visit a set of systems.",
+			Options: append([]*discordgo.ApplicationCommandOption{
+				{Type: discordgo.ApplicationCommandOptionString, Name: "start", Description: "The starting solar system.", Required: true, Autocomplete: true},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "systems", Description: "Comma-separated systems to visit, in any order.", Required: true, Autocomplete: true},
+				{Type: discordgo.ApplicationCommandOptionBoolean, Name: "return", Description: "Finish back at the start system.", Required: false},
+			}, routeFilterOptions()...),
+		},
+		{
+			Name:        "jumproute",
+			Description: "Plans a jump-drive route for capital ships, with fatigue per jump.",
 			Options: []*discordgo.ApplicationCommandOption{
-				{Type: discordgo.ApplicationCommandOptionString, Name: "start", Description: "The starting solar system.", Required: true},
-				{Type: discordgo.ApplicationCommandOptionString, Name: "end", Description: "The destination solar system.", Required: true},
-				{Type: discordgo.ApplicationCommandOptionString, Name: "exclude", Description: "Comma-separated list of systems to avoid", Required: false},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "start", Description: "The starting solar system.", Required: true, Autocomplete: true},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "end", Description: "The destination solar system.", Required: true, Autocomplete: true},
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "preference",
-					Description: "The type of route to prefer.",
-					Required:    false,
+					Name:        "ship",
+					Description: "The jump-capable ship class.",
+					Required:    true,
 					Choices: []*discordgo.ApplicationCommandOptionChoice{
-						{Name: "Safest (High-Sec first)", Value: "safer"},
-						{Name: "Unsafe (Low/Null-Sec first)", Value: "unsafe"},
-						{Name: "Shortest (Default)", Value: "shortest"},
+						{Name: "Carrier / Dreadnought / FAX", Value: "capital"},
+						{Name: "Supercarrier / Titan", Value: "supercapital"},
+						{Name: "Jump Freighter", Value: "jump-freighter"},
+						{Name: "Black Ops", Value: "black-ops"},
+					},
+				},
+				{Type: discordgo.ApplicationCommandOptionInteger, Name: "jdc", Description: "Jump Drive Calibration level (default 5).", Required: false, MinValue: &jdcMinLevel, MaxValue: 5},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "exclude", Description: "Comma-separated systems, regions, constellations or highsec/lowsec/nullsec to avoid", Required: false, Autocomplete: true},
+			},
+		},
+		{
+			Name:        "history",
+			Description: "Shows your recent routes so you can run them again.",
+		},
+		{
+			Name:        "avoid",
+			Description: "Manages the systems every route in this server avoids.",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "add",
+					Description: "Adds systems, regions or security bands to the avoid list.",
+					Options: []*discordgo.ApplicationCommandOption{
+						{Type: discordgo.ApplicationCommandOptionString, Name: "exclude", Description: "Comma-separated systems, regions, constellations or highsec/lowsec/nullsec", Required: true, Autocomplete: true},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "remove",
+					Description: "Removes an entry from the avoid list.",
+					Options: []*discordgo.ApplicationCommandOption{
+						{Type: discordgo.ApplicationCommandOptionString, Name: "entry", Description: "The entry to remove.", Required: true, Autocomplete: true},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "list",
+					Description: "Shows the avoid list.",
+				},
+			},
+		},
+		{
+			Name:        "announce",
+			Description: "Posts wormhole chain changes around your home system to a channel.",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "set",
+					Description: "Turns on chain announcements for this server.",
+					Options: []*discordgo.ApplicationCommandOption{
+						{Type: discordgo.ApplicationCommandOptionString, Name: "home", Description: "Your home system; holes in its chain are announced.", Required: true, Autocomplete: true},
+						{Type: discordgo.ApplicationCommandOptionChannel, Name: "channel", Description: "Channel to post in (defaults to the bot's alert channel).", Required: false, ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText}},
+						{Type: discordgo.ApplicationCommandOptionBoolean, Name: "new_connections", Description: "Announce newly mapped connections (default on).", Required: false},
+						{Type: discordgo.ApplicationCommandOptionBoolean, Name: "eol", Description: "Announce holes going end of life (default on).", Required: false},
+						{Type: discordgo.ApplicationCommandOptionBoolean, Name: "critical_mass", Description: "Announce holes reaching critical mass (default on).", Required: false},
+						{Type: discordgo.ApplicationCommandOptionBoolean, Name: "collapsed", Description: "Announce connections that are gone (default on).", Required: false},
 					},
 				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "off",
+					Description: "Turns off chain announcements for this server.",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "show",
+					Description: "Shows this server's chain announcement settings.",
+				},
+			},
+		},
+		{
+			Name:        "hostile",
+			Description: "Alerts a channel when kills spike in your wormhole chain.",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "set",
+					Description: "Turns on hostile activity alerts for this server.",
+					Options: []*discordgo.ApplicationCommandOption{
+						{Type: discordgo.ApplicationCommandOptionString, Name: "home", Description: "Your home system; its chain is watched.", Required: true, Autocomplete: true},
+						{Type: discordgo.ApplicationCommandOptionInteger, Name: "jumps", Description: fmt.Sprintf("Jumps from home to watch, by stargate or wormhole (default %d).", defaultHostileJumps), Required: false, MinValue: &hostileMinJumps, MaxValue: 10},
+						{Type: discordgo.ApplicationCommandOptionInteger, Name: "kills", Description: fmt.Sprintf("Ship and pod kills in an hour that trigger an alert (default %d).", defaultHostileKills), Required: false, MinValue: &hostileMinKills, MaxValue: 500},
+						{Type: discordgo.ApplicationCommandOptionInteger, Name: "cooldown", Description: fmt.Sprintf("Minutes before the same system alerts again (default %d).", defaultHostileCooldown), Required: false, MinValue: &hostileMinCooldown, MaxValue: 1440},
+						{Type: discordgo.ApplicationCommandOptionChannel, Name: "channel", Description: "Channel to post in (defaults to the bot's alert channel).", Required: false, ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText}},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "off",
+					Description: "Turns off hostile activity alerts for this server.",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "show",
+					Description: "Shows this server's hostile alert settings.",
+				},
+			},
+		},
+		{
+			Name:        "login",
+			Description: "Links your EVE character for autopilot waypoints and routing from your location.",
+		},
+	}
+
+	_, err := sess.ApplicationCommandBulkOverwrite(sess.State.User.ID, "", commands)
+	if err != nil {
+		log.Fatalf("[BOT] FATAL: Could not register slash commands: %v", err)
+	}
+	log.Println("[BOT] Slash commands registered.")
+}
+
+// routeFilterOptions are the avoidance and preference options shared by every routing command.
+func routeFilterOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "exclude", Description: "Comma-separated systems, regions, constellations or highsec/lowsec/nullsec to avoid", Required: false, Autocomplete: true},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "preference",
+			Description: "The type of route to prefer.",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "Safest (High-Sec first)", Value: "safer"},
+				{Name: "Unsafe (Low/Null-Sec first)", Value: "unsafe"},
+				{Name: "Shortest (Default)", Value: "shortest"},
+				{Name: "Least Kills (avoid recent ship/pod kills)", Value: "least-kills"},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "kill_weight",
+			Description: "How hard Least Kills steers around kill activity.",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "Low", Value: "low"},
+				{Name: "Medium (Default)", Value: "medium"},
+				{Name: "High", Value: "high"},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "ship",
+			Description: "Skip wormholes too small for this ship, or at critical mass.",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "Frigate / Destroyer", Value: "frigate"},
+				{Name: "Cruiser / Battlecruiser", Value: "cruiser"},
+				{Name: "Battleship", Value: "battleship"},
+				{Name: "Freighter / Jump Freighter", Value: "freighter"},
+				{Name: "Capital", Value: "capital"},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "min_life",
+			Description: "Skip wormholes with less than this much life left.",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "1 hour", Value: "1h"},
+				{Name: "2 hours", Value: "2h"},
+				{Name: "4 hours", Value: "4h"},
+				{Name: "12 hours", Value: "12h"},
+			},
+		},
+		{Type: discordgo.ApplicationCommandOptionBoolean, Name: "avoid_eol", Description: "Prefer stargates over end-of-life wormholes.", Required: false},
+	}
+}
+
+// ---- interactionCreate (dispatcher) ----
+func (s *Service) interactionCreate(sess *discordgo.Session, i *discordgo.InteractionCreate) {
+	// Button clicks and select menus
+	if i.Type == discordgo.InteractionMessageComponent {
+		var err error
+		customID := i.MessageComponentData().CustomID
+		switch {
+		case strings.HasPrefix(customID, routeSelectPrefix):
+			err = s.handleRouteSelect(sess, i)
+		case strings.HasPrefix(customID, routePagePrefix):
+			err = s.handleRoutePage(sess, i)
+		case strings.HasPrefix(customID, routeRerunPrefix):
+			err = s.handleRouteRerun(sess, i)
+		case strings.HasPrefix(customID, copyRoutePrefix):
+			err = s.handleCopyRoute(sess, i)
+		case strings.HasPrefix(customID, setDestinationPrefix):
+			err = s.handleSetDestination(sess, i)
+		case customID == "copy_route_button":
+			// buttons on embeds from before routes were cached
+			err = s.respondEphemeral(sess, i, "This route has expired. Please run /route again.")
+		}
+		if err != nil {
+			log.Printf("[BOT] ERROR handling component: %v", err)
+		}
+		return
+	}
+
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		if err := s.handleAutocomplete(sess, i); err != nil {
+			log.Printf("[BOT] ERROR handling autocomplete: %v", err)
+		}
+		return
+	}
+
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+	var handler func(*discordgo.Session, *discordgo.InteractionCreate) error
+	var flags discordgo.MessageFlags
+	switch i.ApplicationCommandData().Name {
+	case "route":
+		handler = s.handleRouteCommand
+	case "trip":
+		handler = s.handleTripCommand
+	case "tour":
+		handler = s.handleTourCommand
+	case "jumproute":
+		handler = s.handleJumpRouteCommand
+	case "history":
+		handler = s.handleHistoryCommand
+		flags = discordgo.MessageFlagsEphemeral
+	case "avoid":
+		handler = s.handleAvoidCommand
+	case "announce":
+		handler = s.handleAnnounceCommand
+	case "hostile":
+		handler = s.handleHostileCommand
+	case "login":
+		handler = s.handleLoginCommand
+		flags = discordgo.MessageFlagsEphemeral
+	default:
+		return
+	}
+
+	if err := sess.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: flags},
+	}); err != nil {
+		log.Printf("[BOT] ERROR: Failed to defer interaction response: %v", err)
+		return
+	}
+
+	// Process the routing command
+	if err := handler(sess, i); err != nil {
+		log.Printf("[BOT] ERROR processing %s: %v", i.ApplicationCommandData().Name, err)
+	}
+}
+
+// ---- autocomplete handler: system names ----
+func (s *Service) handleAutocomplete(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 1 && options[0].Type == discordgo.ApplicationCommandOptionSubCommand {
+		options = options[0].Options
+	}
+	var focused *discordgo.ApplicationCommandInteractionDataOption
+	for _, opt := range options {
+		if opt.Focused {
+			focused = opt
+			break
+		}
+	}
+
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	if focused != nil && focused.Name == "entry" {
+		// /avoid remove: offer the current entries
+		typed := strings.ToLower(focused.StringValue())
+		for _, entry := range s.guildAvoid(i.GuildID) {
+			if strings.Contains(strings.ToLower(entry), typed) && len(choices) < maxAutocompleteChoices {
+				choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: entry, Value: entry})
+			}
+		}
+	} else if focused != nil {
+		typed := focused.StringValue()
+
+		// for lists, complete the entry being typed and keep the ones before it
+		prefix := ""
+		if listOptions[focused.Name] {
+			if cut := strings.LastIndex(typed, ","); cut >= 0 {
+				prefix = strings.TrimSpace(typed[:cut]) + ", "
+				typed = typed[cut+1:]
+			}
+		}
+
+		for _, sys := range s.systems.Suggest(typed, maxAutocompleteChoices) {
+			value := prefix + sys.Name
+			if len(value) > 100 { // Discord's limit on choice values
+				continue
+			}
+			label := fmt.Sprintf("%s (%.1f)", value, sys.Security)
+			if len(label) > 100 {
+				label = value
+			}
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: label, Value: value})
+		}
+	}
+
+	return sess.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	})
+}
+
+// ---- button handler: Copy Route ----
+func (s *Service) handleCopyRoute(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	// CustomID is copy_route:<route ID>:<alternative>:<format>
+	parts := strings.Split(strings.TrimPrefix(i.MessageComponentData().CustomID, copyRoutePrefix), ":")
+	if len(parts) != 3 {
+		return s.respondEphemeral(sess, i, "This route has expired. Please run /route again.")
+	}
+	routeID, format := parts[0], parts[2]
+	index, _ := strconv.Atoi(parts[1])
+
+	path := s.routePath(routeID, index)
+	if path == nil {
+		return s.respondEphemeral(sess, i, "This route has expired. Please run /route again.")
+	}
+
+	// The first click opens an ephemeral message; the format buttons on it update that message.
+	responseType := discordgo.InteractionResponseChannelMessageWithSource
+	if i.Message != nil && i.Message.Flags&discordgo.MessageFlagsEphemeral != 0 {
+		responseType = discordgo.InteractionResponseUpdateMessage
+	}
+
+	var buttons []discordgo.MessageComponent
+	for _, f := range copyFormats {
+		buttons = append(buttons, discordgo.Button{
+			Label:    f.Label,
+			Style:    discordgo.SecondaryButton,
+			CustomID: copyRouteCustomID(routeID, index, f.Key),
+			Disabled: f.Key == format,
+		})
+	}
+
+	return sess.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: responseType,
+		Data: &discordgo.InteractionResponseData{
+			Content:    s.formatCopyText(path, format),
+			Flags:      discordgo.MessageFlagsEphemeral,
+			Components: []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}},
+		},
+	})
+}
+
+// routePath finds an alternative of a route in the cache, falling back to the
+// saved history (best route only) once the cache entry has gone.
+func (s *Service) routePath(routeID string, index int) []int {
+	if rc, ok := s.routes.Get(routeID); ok {
+		if index >= 0 && index < len(rc.Paths) {
+			return rc.Paths[index]
+		}
+		return nil
+	}
+	if index != 0 {
+		return nil
+	}
+	if rec, err := s.store.GetRoute(routeID); err == nil {
+		return rec.Path
+	}
+	return nil
+}
+
+// ---- button handler: Set Destination ----
+func (s *Service) handleSetDestination(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if s.sso == nil {
+		return s.respondEphemeral(sess, i, "Setting destinations is not enabled on this bot.")
+	}
+	// CustomID is route_dest:<route ID>:<alternative>
+	parts := strings.Split(strings.TrimPrefix(i.MessageComponentData().CustomID, setDestinationPrefix), ":")
+	index := 0
+	if len(parts) > 1 {
+		index, _ = strconv.Atoi(parts[1])
+	}
+	path := s.routePath(parts[0], index)
+	if len(path) < 2 {
+		return s.respondEphemeral(sess, i, "This route has expired. Please run /route again.")
+	}
+
+	// Refreshing the login and setting one waypoint per system can both take
+	// a while, so answer Discord first.
+	if err := sess.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}); err != nil {
+		return fmt.Errorf("failed to defer set destination response: %w", err)
+	}
+
+	char, err := s.sso.Character(interactionUserID(i))
+	if err != nil {
+		description := "You have no linked character. Use /login first."
+		if !errors.Is(err, ErrNotLinked) {
+			log.Printf("[BOT] WARN: %v", err)
+			description = "Could not refresh your character's login. Please use /login again."
+		}
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: description,
+			Color:       0xff0000,
+		}, nil)
+	}
+
+	// The first system is where the route starts, so only the rest become waypoints.
+	embed := &discordgo.MessageEmbed{
+		Author:      embedAuthor,
+		Title:       "Destination Set",
+		Description: fmt.Sprintf("Sent %d waypoints to **%s**, ending in **%s**.", len(path)-1, char.CharacterName, s.systemName(path[len(path)-1])),
+		Color:       0x4CAF50,
+	}
+	if err := s.sso.SetWaypoints(char, path[1:]); err != nil {
+		log.Printf("[BOT] WARN: setting waypoints for %s: %v", char.CharacterName, err)
+		embed = &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Title:       "Error: Could Not Set Destination",
+			Description: fmt.Sprintf("ESI rejected the waypoints for **%s**: %v", char.CharacterName, err),
+			Color:       0xff0000,
+		}
+	}
+	return s.editResponse(sess, i, embed, nil)
+}
+
+// ---- login handler: link an EVE character ----
+func (s *Service) handleLoginCommand(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if s.sso == nil {
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: "EVE SSO is not configured on this bot.",
+			Color:       0xff0000,
+		}, nil)
+	}
+
+	loginURL, err := s.sso.LoginURL(interactionUserID(i))
+	if err != nil {
+		return err
+	}
+	embed := &discordgo.MessageEmbed{
+		Author:      embedAuthor,
+		Title:       "Link Your EVE Character",
+		Description: "Log in with EVE Online to let the bot set autopilot waypoints for your character and route from its location. The link is valid for 10 minutes.",
+		Color:       0x4CAF50,
+	}
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Log in with EVE Online", Style: discordgo.LinkButton, URL: loginURL},
+			},
+		},
+	}
+	return s.editResponse(sess, i, embed, components)
+}
+
+// ---- select menu handler: alternative routes ----
+func (s *Service) handleRouteSelect(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	data := i.MessageComponentData()
+	parts := strings.Split(strings.TrimPrefix(data.CustomID, routeSelectPrefix), ":")
+	view := routeView{Compact: len(parts) > 1 && parts[1] == "1"}
+	if len(data.Values) > 0 {
+		view.Index, _ = strconv.Atoi(data.Values[0])
+	}
+	return s.updateRouteMessage(sess, i, parts[0], view)
+}
+
+// ---- button handler: route pages and compact mode ----
+func (s *Service) handleRoutePage(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	// CustomID is route_page:<route ID>:<alternative>:<page>:<compact>
+	parts := strings.Split(strings.TrimPrefix(i.MessageComponentData().CustomID, routePagePrefix), ":")
+	if len(parts) != 4 {
+		return s.respondEphemeral(sess, i, "This route has expired. Please run /route again.")
+	}
+	var view routeView
+	view.Index, _ = strconv.Atoi(parts[1])
+	view.Page, _ = strconv.Atoi(parts[2])
+	view.Compact = parts[3] == "1"
+	return s.updateRouteMessage(sess, i, parts[0], view)
+}
+
+func routePageCustomID(routeID string, view routeView) string {
+	return fmt.Sprintf("%s%s:%d:%d:%d", routePagePrefix, routeID, view.Index, view.Page, boolToInt(view.Compact))
+}
+
+// updateRouteMessage redraws a route embed in place with a different view.
+func (s *Service) updateRouteMessage(sess *discordgo.Session, i *discordgo.InteractionCreate, routeID string, view routeView) error {
+	rc, ok := s.routes.Get(routeID)
+	if !ok {
+		return s.respondEphemeral(sess, i, "This route has expired. Please run /route again.")
+	}
+
+	embed, components := s.renderRoute(rc, view)
+	return sess.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+		},
+	})
+}
+
+// ---- main route handler ----
+func (s *Service) handleRouteCommand(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	opts := s.parseOptions(i.ApplicationCommandData().Options)
+
+	// without a start system, route from the linked character's location
+	var note string
+	if opts["start"] == "" {
+		name, charName, problem := s.characterSystem(i)
+		if problem != "" {
+			return s.editResponse(sess, i, &discordgo.MessageEmbed{
+				Author:      embedAuthor,
+				Title:       "Error: No Start System",
+				Description: problem,
+				Color:       0xff0000,
+			}, nil)
+		}
+		opts["start"] = name
+		note = fmt.Sprintf("Starting from **%s**'s current location.", charName)
+	}
+	if problems := s.checkSystemNames(opts, "start", "end", "exclude"); len(problems) > 0 {
+		return s.editResponse(sess, i, unresolvedSystemsEmbed(problems), nil)
+	}
+	startName, endName := opts["start"], opts["end"]
+
+	startID, err1 := s.esiClient.GetSystemID(startName)
+	endID, err2 := s.esiClient.GetSystemID(endName)
+
+	var embed *discordgo.MessageEmbed
+	var components []discordgo.MessageComponent
+
+	// names ESI could not resolve after all
+	if err1 != nil {
+		embed = invalidSystemEmbed(startName)
+	} else if err2 != nil {
+		embed = invalidSystemEmbed(endName)
+	} else if rc := s.planCachedRoute(i.ID, i.GuildID, startID, endID, opts); rc == nil {
+		embed = &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: fmt.Sprintf("No route possible between **%s** and **%s**.", startName, endName),
+			Color:       0xff0000,
+		}
+	} else {
+		rc.Note = note
+		rc.Rerunnable = s.saveRouteHistory(i, rc, startID, endID, opts)
+		s.routes.Put(rc)
+		embed, components = s.renderRoute(rc, routeView{})
+	}
+
+	return s.editResponse(sess, i, embed, components)
+}
+
+// characterSystem looks up the name of the system the user's linked character is in.
+// On failure it returns a message explaining what the user needs to do.
+func (s *Service) characterSystem(i *discordgo.InteractionCreate) (systemName, charName, problem string) {
+	if s.sso == nil {
+		return "", "", "Please give a start system. Routing from your character's location is not enabled on this bot."
+	}
+	char, err := s.sso.Character(interactionUserID(i))
+	if errors.Is(err, ErrNotLinked) {
+		return "", "", "Please give a start system, or use /login to link a character and route from its location."
+	}
+	if err != nil {
+		log.Printf("[BOT] WARN: %v", err)
+		return "", "", "Could not refresh your character's login. Please use /login again."
+	}
+	systemID, err := s.sso.CharacterLocation(char)
+	if errors.Is(err, ErrMissingScope) {
+		return "", "", fmt.Sprintf("**%s** was linked without location access. Please use /login again.", char.CharacterName)
+	}
+	if err != nil {
+		log.Printf("[BOT] WARN: location of %s: %v", char.CharacterName, err)
+		return "", "", fmt.Sprintf("Could not find **%s**'s location. Please give a start system.", char.CharacterName)
+	}
+	return s.systemName(systemID), char.CharacterName, ""
+}
+
+// planCachedRoute runs a /route query against the current graph snapshot.
+// It returns nil when no route exists.
+func (s *Service) planCachedRoute(id, guildID string, startID, endID int, opts map[string]string) *cachedRoute {
+	routeOpts := s.routeOptionsFrom(guildID, opts)
+	routeOpts.Alternatives = maxRouteAlternatives - 1
+
+	// pathfinding against the current immutable snapshot
+	graph := s.graphStore.Snapshot()
+	route := PlanRoute(graph, startID, endID, s.esiClient, routeOpts)
+	if route.Path == nil {
+		return nil
+	}
+
+	rc := &cachedRoute{
+		ID:            id,
+		StartName:     opts["start"],
+		EndName:       opts["end"],
+		Ship:          routeOpts.Ship,
+		Avoid:         routeOpts.Avoid,
+		Excluded:      strings.Join(s.avoidEntries(guildID, opts["exclude"]), ", "),
+		AlwaysAvoided: s.guildAvoid(guildID),
+		Paths:         append([][]int{route.Path}, route.Alternatives...),
+		Skipped:       route.Skipped,
+		CreatedAt:     time.Now(),
+	}
+	for _, path := range rc.Paths {
+		rc.Summaries = append(rc.Summaries, s.summarizeRoute(graph, path))
+	}
+	return rc
+}
+
+// saveRouteHistory stores a /route result in the invoking user's history.
+// It reports whether the route was saved and can be re-run.
+func (s *Service) saveRouteHistory(i *discordgo.InteractionCreate, rc *cachedRoute, startID, endID int, opts map[string]string) bool {
+	err := s.store.SaveRoute(&RouteRecord{
+		ID:        rc.ID,
+		GuildID:   i.GuildID,
+		UserID:    interactionUserID(i),
+		Options:   opts,
+		StartID:   startID,
+		EndID:     endID,
+		Path:      rc.Paths[0],
+		CreatedAt: rc.CreatedAt,
+	}, routeHistoryKeep)
+	if err != nil {
+		log.Printf("[BOT] WARN: could not save route history: %v", err)
+		return false
+	}
+	return true
+}
+
+// ---- history handler: recent routes ----
+func (s *Service) handleHistoryCommand(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	records, err := s.store.RouteHistory(i.GuildID, interactionUserID(i), routeHistoryLimit)
+	if err != nil {
+		return fmt.Errorf("could not load route history: %w", err)
+	}
+	if len(records) == 0 {
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Title:       "Route History",
+			Description: "You have no saved routes yet. Use /route to plan one.",
+			Color:       0x4CAF50,
+		}, nil)
+	}
+
+	lines := make([]string, len(records))
+	menuOptions := make([]discordgo.SelectMenuOption, len(records))
+	for n, rec := range records {
+		jumps := len(rec.Path) - 1
+		lines[n] = fmt.Sprintf("%d. **%s → %s** · %d jumps · <t:%d:R>", n+1, rec.Options["start"], rec.Options["end"], jumps, rec.CreatedAt.Unix())
+		menuOptions[n] = discordgo.SelectMenuOption{
+			Label:       fmt.Sprintf("%s → %s", rec.Options["start"], rec.Options["end"]),
+			Description: fmt.Sprintf("%d jumps · %s", jumps, rec.CreatedAt.UTC().Format("2 Jan 15:04 MST")),
+			Value:       rec.ID,
+		}
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Author:      embedAuthor,
+		Title:       "Route History",
+		Description: strings.Join(lines, "\n"),
+		Color:       0x4CAF50,
+	}
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					MenuType:    discordgo.StringSelectMenu,
+					CustomID:    routeRerunPrefix,
+					Placeholder: "Re-run a route against the current map",
+					Options:     menuOptions,
+				},
 			},
 		},
 	}
+	return s.editResponse(sess, i, embed, components)
+}
+
+// ---- re-run handler: Re-run button and /history menu ----
+func (s *Service) handleRouteRerun(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	data := i.MessageComponentData()
+	routeID := strings.TrimPrefix(data.CustomID, routeRerunPrefix)
+	if routeID == "" && len(data.Values) > 0 {
+		routeID = data.Values[0]
+	}
+
+	rec, err := s.store.GetRoute(routeID)
+	if errors.Is(err, ErrNotFound) {
+		return s.respondEphemeral(sess, i, "That route is no longer in the history. Please run /route again.")
+	}
+	if err != nil {
+		return fmt.Errorf("could not load route %s: %w", routeID, err)
+	}
+
+	if err := sess.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}); err != nil {
+		return fmt.Errorf("failed to defer re-run response: %w", err)
+	}
+
+	rc := s.planCachedRoute(i.ID, i.GuildID, rec.StartID, rec.EndID, rec.Options)
+	if rc == nil {
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author: embedAuthor,
+			Description: fmt.Sprintf("No route possible between **%s** and **%s** any more. It was %d jumps <t:%d:R>.",
+				rec.Options["start"], rec.Options["end"], len(rec.Path)-1, rec.CreatedAt.Unix()),
+			Color: 0xff0000,
+		}, nil)
+	}
+
+	rc.Title = "Route Re-run"
+	rc.Note = s.describeRouteChange(rec.Path, rc.Paths[0], rec.CreatedAt)
+	rc.Changed = make(map[int]bool)
+	previous := make(map[int]bool, len(rec.Path))
+	for _, id := range rec.Path {
+		previous[id] = true
+	}
+	for _, id := range rc.Paths[0] {
+		if !previous[id] {
+			rc.Changed[id] = true
+		}
+	}
+	rc.Rerunnable = s.saveRouteHistory(i, rc, rec.StartID, rec.EndID, rec.Options)
+	s.routes.Put(rc)
+
+	embed, components := s.renderRoute(rc, routeView{})
+	return s.editResponse(sess, i, embed, components)
+}
+
+// maxChangeNames caps how many systems describeRouteChange names per line.
+const maxChangeNames = 5
+
+// describeRouteChange summarises how a route differs from when it was last run.
+func (s *Service) describeRouteChange(before, after []int, since time.Time) string {
+	if equalPaths(before, after) {
+		return fmt.Sprintf("No change since <t:%d:R>: same %d-jump route.", since.Unix(), len(after)-1)
+	}
+
+	inBefore := make(map[int]bool, len(before))
+	for _, id := range before {
+		inBefore[id] = true
+	}
+	inAfter := make(map[int]bool, len(after))
+	for _, id := range after {
+		inAfter[id] = true
+	}
+
+	lines := []string{fmt.Sprintf("**Route changed** since <t:%d:R>: %d → %d jumps.", since.Unix(), len(before)-1, len(after)-1)}
+	if added := s.namesNotIn(after, inBefore); added != "" {
+		lines = append(lines, "🆕 Now via: "+added)
+	}
+	if removed := s.namesNotIn(before, inAfter); removed != "" {
+		lines = append(lines, "➖ No longer via: "+removed)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// namesNotIn lists the names of systems on path that are not in exclude.
+func (s *Service) namesNotIn(path []int, exclude map[int]bool) string {
+	var names []string
+	extra := 0
+	for _, id := range path {
+		if exclude[id] {
+			continue
+		}
+		if len(names) == maxChangeNames {
+			extra++
+			continue
+		}
+		names = append(names, s.systemName(id))
+	}
+	if extra > 0 {
+		names = append(names, fmt.Sprintf("and %d more", extra))
+	}
+	return strings.Join(names, ", ")
+}
+
+// ---- trip handler: ordered waypoints ----
+func (s *Service) handleTripCommand(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	opts := s.parseOptions(i.ApplicationCommandData().Options)
+	if problems := s.checkSystemNames(opts, "start", "via", "end", "exclude"); len(problems) > 0 {
+		return s.editResponse(sess, i, unresolvedSystemsEmbed(problems), nil)
+	}
+
+	stopNames := []string{opts["start"]}
+	for _, name := range strings.Split(opts["via"], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			stopNames = append(stopNames, name)
+		}
+	}
+	if len(stopNames)-1 > maxTripWaypoints {
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: fmt.Sprintf("A trip can go through at most %d waypoints.", maxTripWaypoints),
+			Color:       0xff0000,
+		}, nil)
+	}
+	stopNames = append(stopNames, opts["end"])
+
+	stopIDs := make([]int, len(stopNames))
+	for n, name := range stopNames {
+		id, err := s.esiClient.GetSystemID(name)
+		if err != nil {
+			return s.editResponse(sess, i, invalidSystemEmbed(name), nil)
+		}
+		stopIDs[n] = id
+	}
+
+	guildID := i.GuildID
+	routeOpts := s.routeOptionsFrom(guildID, opts)
+	graph := s.graphStore.Snapshot()
+	trip := []int{stopIDs[0]}
+	legs := make([]tripLeg, 0, len(stopIDs)-1)
+	skipped := make(map[[2]int]SkippedJump)
+	for n := 1; n < len(stopIDs); n++ {
+		leg := PlanRoute(graph, stopIDs[n-1], stopIDs[n], s.esiClient, routeOpts)
+		if leg.Path == nil {
+			return s.editResponse(sess, i, &discordgo.MessageEmbed{
+				Author:      embedAuthor,
+				Description: fmt.Sprintf("No route possible between **%s** and **%s** (leg %d).", stopNames[n-1], stopNames[n], n),
+				Color:       0xff0000,
+			}, nil)
+		}
+		trip = append(trip, leg.Path[1:]...)
+		legs = append(legs, tripLeg{From: stopNames[n-1], To: stopNames[n], Jumps: len(leg.Path) - 1})
+		for _, sj := range leg.Skipped {
+			skipped[connectionKey(sj.Edge.FromID, sj.Edge.ToID)] = sj
+		}
+	}
+
+	rc := &cachedRoute{
+		ID:            i.ID,
+		Title:         "Trip Calculated",
+		StartName:     stopNames[0],
+		EndName:       stopNames[len(stopNames)-1],
+		Ship:          routeOpts.Ship,
+		Avoid:         routeOpts.Avoid,
+		Excluded:      strings.Join(s.avoidEntries(guildID, opts["exclude"]), ", "),
+		AlwaysAvoided: s.guildAvoid(guildID),
+		Paths:         [][]int{trip},
+		Skipped:       sortedSkipped(skipped),
+		Legs:          legs,
+		CreatedAt:     time.Now(),
+	}
+	rc.Summaries = []routeSummary{s.summarizeRoute(graph, trip)}
+	s.routes.Put(rc)
+
+	embed, components := s.renderRoute(rc, routeView{})
+	return s.editResponse(sess, i, embed, components)
+}
+
+// ---- tour handler: unordered set of systems ----
+func (s *Service) handleTourCommand(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	opts := s.parseOptions(i.ApplicationCommandData().Options)
+	if problems := s.checkSystemNames(opts, "start", "systems", "exclude"); len(problems) > 0 {
+		return s.editResponse(sess, i, unresolvedSystemsEmbed(problems), nil)
+	}
+	startName := opts["start"]
+	roundTrip := opts["return"] == "true"
+
+	var stopNames []string
+	for _, name := range strings.Split(opts["systems"], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			stopNames = append(stopNames, name)
+		}
+	}
+	if len(stopNames) > maxTourStops {
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: fmt.Sprintf("A tour can visit at most %d systems.", maxTourStops),
+			Color:       0xff0000,
+		}, nil)
+	}
+
+	names := make(map[int]string)
+	startID, err := s.esiClient.GetSystemID(startName)
+	if err != nil {
+		return s.editResponse(sess, i, invalidSystemEmbed(startName), nil)
+	}
+	names[startID] = startName
+	stopIDs := make([]int, 0, len(stopNames))
+	for _, name := range stopNames {
+		id, err := s.esiClient.GetSystemID(name)
+		if err != nil {
+			return s.editResponse(sess, i, invalidSystemEmbed(name), nil)
+		}
+		names[id] = name
+		stopIDs = append(stopIDs, id)
+	}
+
+	guildID := i.GuildID
+	routeOpts := s.routeOptionsFrom(guildID, opts)
+	graph := s.graphStore.Snapshot()
+	tour, err := PlanTour(graph, startID, stopIDs, s.esiClient, routeOpts, roundTrip)
+	if err != nil {
+		log.Printf("[BOT] Tour from %s failed: %v", startName, err)
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: "No tour possible: at least one of those systems can't be reached with the current exclusions.",
+			Color:       0xff0000,
+		}, nil)
+	}
+
+	rc := &cachedRoute{
+		ID:            i.ID,
+		Title:         "Tour Calculated",
+		Note:          tourNote(tour),
+		StartName:     startName,
+		EndName:       names[tour.Order[len(tour.Order)-1]],
+		Ship:          routeOpts.Ship,
+		Avoid:         routeOpts.Avoid,
+		Excluded:      strings.Join(s.avoidEntries(guildID, opts["exclude"]), ", "),
+		AlwaysAvoided: s.guildAvoid(guildID),
+		Paths:         [][]int{tour.Path},
+		CreatedAt:     time.Now(),
+	}
+	for n, leg := range tour.Legs {
+		rc.Legs = append(rc.Legs, tripLeg{From: names[tour.Order[n]], To: names[tour.Order[n+1]], Jumps: len(leg) - 1})
+	}
+	rc.Summaries = []routeSummary{s.summarizeRoute(graph, tour.Path)}
+	s.routes.Put(rc)
+
+	embed, components := s.renderRoute(rc, routeView{})
+	return s.editResponse(sess, i, embed, components)
+}
+
+// tourNote says which solver ordered the tour: Held-Karp is exact, the
+// nearest-neighbour + 2-opt fallback for larger tours is not.
+func tourNote(tour *TourResult) string {
+	if tour.Optimal {
+		return "Visiting order is optimal."
+	}
+	return fmt.Sprintf("Visiting order found heuristically (nearest neighbour + 2-opt); orders are only exact for up to %d stops.", exactTourLimit)
+}
+
+// ---- jump route handler: capital jump drives ----
+func (s *Service) handleJumpRouteCommand(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	opts := s.parseOptions(i.ApplicationCommandData().Options)
+	if problems := s.checkSystemNames(opts, "start", "end", "exclude"); len(problems) > 0 {
+		return s.editResponse(sess, i, unresolvedSystemsEmbed(problems), nil)
+	}
+	startName, endName := opts["start"], opts["end"]
+	ship, ok := jumpShips[opts["ship"]]
+	if !ok {
+		ship = jumpShips["capital"]
+	}
+	jdcLevel := 5
+	if v, err := strconv.Atoi(opts["jdc"]); err == nil && v >= 0 && v <= 5 {
+		jdcLevel = v
+	}
+
+	startID, err := s.esiClient.GetSystemID(startName)
+	if err != nil {
+		return s.editResponse(sess, i, invalidSystemEmbed(startName), nil)
+	}
+	endID, err := s.esiClient.GetSystemID(endName)
+	if err != nil {
+		return s.editResponse(sess, i, invalidSystemEmbed(endName), nil)
+	}
+
+	avoidList := s.buildAvoidList(strings.Join(s.avoidEntries(i.GuildID, opts["exclude"]), ","))
+	canEnter := func(id int) bool {
+		return !avoidList[id] && s.jumpDestinationAllowed(id)
+	}
+
+	positions := s.positions.All()
+	maxRange := ship.JumpRange(jdcLevel)
+	path, err := PlanJumpRoute(positions, startID, endID, maxRange, canEnter)
+	if err != nil {
+		desc := fmt.Sprintf("No jump route possible between **%s** and **%s**: %v.", startName, endName, err)
+		if len(positions) == 0 {
+			desc = "System coordinates are still being loaded. Please try again in a few minutes."
+		}
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: desc,
+			Color:       0xff0000,
+		}, nil)
+	}
+
+	legs := JumpLegs(positions, path, ship)
+	totalLY := 0.0
+	legLines := make([]string, len(legs))
+	for n, leg := range legs {
+		totalLY += leg.LightYears
+		legLines[n] = fmt.Sprintf("%d. %s → **%s**: %.2f LY · fatigue %s · wait %s",
+			n+1, s.systemName(leg.FromID), s.systemName(leg.ToID), leg.LightYears, shortDuration(leg.Fatigue), shortDuration(leg.Reactivation))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Author:    embedAuthor,
+		Title:     "Jump Route Calculated",
+		Color:     0x4CAF50,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Start", Value: startName, Inline: true},
+			{Name: "End", Value: endName, Inline: true},
+			{Name: "Jumps", Value: fmt.Sprintf("%d", len(legs)), Inline: true},
+			{Name: "Ship", Value: ship.Name, Inline: true},
+			{Name: "Range", Value: fmt.Sprintf("%.2f LY (JDC %d)", maxRange, jdcLevel), Inline: true},
+			{Name: "Distance", Value: fmt.Sprintf("%.2f LY", totalLY), Inline: true},
+			{Name: "Jumps & Fatigue", Value: strings.Join(legLines, "\n")},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "High-sec, Pochven, Zarzakh and wormhole space are never jump destinations. Fatigue assumes you jump as soon as the drive is ready.",
+		},
+	}
+	return s.editResponse(sess, i, embed, nil)
+}
+
+// jumpDestinationAllowed reports whether a jump drive can jump into a system:
+// known-space low or null security, outside Pochven and Zarzakh.
+func (s *Service) jumpDestinationAllowed(systemID int) bool {
+	if systemID < 30000000 || systemID >= 31000000 || systemID == zarzakhSystemID {
+		return false
+	}
+	if s.locations[systemID].RegionID == pochvenRegionID {
+		return false
+	}
+	sysInfo, err := s.esiClient.GetSystemDetails(systemID)
+	return err == nil && sysInfo.SecurityStatus < 0.45
+}
+
+// maxAvoidEntries caps the size of a guild's always-avoid list.
+const maxAvoidEntries = 25
+
+// guildAvoid returns a guild's always-avoid entries, or the bot's default set
+// if the guild has never changed it (and in DMs). The result is always a fresh
+// slice: callers append to it and keep it in cached routes, and the default set
+// is shared by every interaction.
+func (s *Service) guildAvoid(guildID string) []string {
+	if guildID == "" {
+		return slices.Clone(s.defaultAvoid)
+	}
+	entries, ok, err := s.store.GuildAvoidList(guildID)
+	if err != nil {
+		log.Printf("[BOT] WARN: could not load avoid list for guild %s: %v", guildID, err)
+		return slices.Clone(s.defaultAvoid)
+	}
+	if !ok {
+		return slices.Clone(s.defaultAvoid)
+	}
+	return entries
+}
+
+// avoidEntries combines the guild's always-avoid list with a command's
+// comma-separated exclude option, dropping repeats.
+func (s *Service) avoidEntries(guildID, exclude string) []string {
+	var entries []string
+	seen := make(map[string]bool)
+	for _, entry := range append(s.guildAvoid(guildID), strings.Split(exclude, ",")...) {
+		entry = strings.TrimSpace(entry)
+		if key := strings.ToLower(entry); entry != "" && !seen[key] {
+			seen[key] = true
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// ---- avoid handler: per-guild always-avoid list ----
+func (s *Service) handleAvoidCommand(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if i.GuildID == "" {
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: "The avoid list is set per server. Use /avoid in a server channel.",
+			Color:       0xff0000,
+		}, nil)
+	}
+
+	sub := i.ApplicationCommandData().Options[0]
+	opts := s.parseOptions(sub.Options)
+	entries := s.guildAvoid(i.GuildID)
+
+	if sub.Name != "list" && (i.Member == nil || i.Member.Permissions&discordgo.PermissionManageServer == 0) {
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: "You need the Manage Server permission to change the avoid list.",
+			Color:       0xff0000,
+		}, nil)
+	}
+
+	// Only a change is saved, so an untouched server keeps following DEFAULT_AVOID.
+	var note string
+	changed := false
+	switch sub.Name {
+	case "add":
+		if problems := s.checkSystemNames(opts, "exclude"); len(problems) > 0 {
+			return s.editResponse(sess, i, unresolvedSystemsEmbed(problems), nil)
+		}
+		before := len(entries)
+		entries = s.avoidEntries(i.GuildID, opts["exclude"])
+		if len(entries) > maxAvoidEntries {
+			return s.editResponse(sess, i, &discordgo.MessageEmbed{
+				Author:      embedAuthor,
+				Description: fmt.Sprintf("The avoid list can hold at most %d entries.", maxAvoidEntries),
+				Color:       0xff0000,
+			}, nil)
+		}
+		note = fmt.Sprintf("Added %d entries.", len(entries)-before)
+		changed = len(entries) != before
+	case "remove":
+		var kept []string
+		for _, entry := range entries {
+			if !strings.EqualFold(entry, strings.TrimSpace(opts["entry"])) {
+				kept = append(kept, entry)
+			}
+		}
+		if len(kept) == len(entries) {
+			note = fmt.Sprintf("**%s** is not on the avoid list.", opts["entry"])
+		} else {
+			note = fmt.Sprintf("Removed **%s**.", opts["entry"])
+			changed = true
+		}
+		entries = kept
+	}
+
+	if changed {
+		if err := s.store.SetGuildAvoidList(i.GuildID, entries); err != nil {
+			return fmt.Errorf("could not save avoid list: %w", err)
+		}
+	}
+
+	list := "Nothing is avoided by default."
+	if len(entries) > 0 {
+		list = "• " + strings.Join(entries, "\n• ")
+	}
+	return s.editResponse(sess, i, &discordgo.MessageEmbed{
+		Author:      embedAuthor,
+		Title:       "Always Avoided in This Server",
+		Description: strings.TrimSpace(note + "\n\n" + list),
+		Color:       0x4CAF50,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Every route in this server avoids these, on top of each command's exclude option.",
+		},
+	}, nil)
+}
+
+// announceEvents lists the chain events in display order, with the /announce
+// option that toggles each one.
+var announceEvents = []struct{ Key, Option, Label string }{
+	{announceNewConnection, "new_connections", "New connections"},
+	{announceEOL, "eol", "End of life"},
+	{announceCriticalMass, "critical_mass", "Critical mass"},
+	{announceCollapsed, "collapsed", "Connections gone"},
+}
+
+// ---- announce handler: per-guild chain announcements ----
+func (s *Service) handleAnnounceCommand(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if i.GuildID == "" {
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: "Chain announcements are set per server. Use /announce in a server channel.",
+			Color:       0xff0000,
+		}, nil)
+	}
+
+	sub := i.ApplicationCommandData().Options[0]
+	opts := s.parseOptions(sub.Options)
+
+	if sub.Name != "show" && (i.Member == nil || i.Member.Permissions&discordgo.PermissionManageServer == 0) {
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: "You need the Manage Server permission to change chain announcements.",
+			Color:       0xff0000,
+		}, nil)
+	}
+
+	previous, err := s.store.GetAnnounceConfig(i.GuildID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("could not load announcement settings: %w", err)
+	}
+
+	switch sub.Name {
+	case "off":
+		if err := s.store.DeleteAnnounceConfig(i.GuildID); err != nil {
+			return fmt.Errorf("could not delete announcement settings: %w", err)
+		}
+		if previous != nil {
+			s.releaseWebhook(sess, i.GuildID, previous.ChannelID)
+		}
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: "Chain announcements are off for this server.",
+			Color:       0x4CAF50,
+		}, nil)
+
+	case "set":
+		if problems := s.checkSystemNames(opts, "home"); len(problems) > 0 {
+			return s.editResponse(sess, i, unresolvedSystemsEmbed(problems), nil)
+		}
+		homeID, err := s.esiClient.GetSystemID(opts["home"])
+		if err != nil {
+			return s.editResponse(sess, i, invalidSystemEmbed(opts["home"]), nil)
+		}
+
+		cfg := AnnounceConfig{HomeID: homeID, HomeName: opts["home"], ChannelID: opts["channel"], Events: make(map[string]bool)}
+		for _, event := range announceEvents {
+			cfg.Events[event.Key] = opts[event.Option] != "false"
+		}
+
+		webhookURL, problem := s.alertWebhook(sess, opts["channel"])
+		if problem != nil {
+			return s.editResponse(sess, i, problem, nil)
+		}
+		cfg.WebhookURL = webhookURL
+
+		if err := s.store.SaveAnnounceConfig(i.GuildID, cfg); err != nil {
+			return fmt.Errorf("could not save announcement settings: %w", err)
+		}
+		if previous != nil && previous.ChannelID != cfg.ChannelID {
+			s.releaseWebhook(sess, i.GuildID, previous.ChannelID)
+		}
+	}
+
+	cfg, err := s.store.GetAnnounceConfig(i.GuildID)
+	if errors.Is(err, ErrNotFound) {
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: "Chain announcements are off for this server. Turn them on with /announce set.",
+			Color:       0x4CAF50,
+		}, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("could not load announcement settings: %w", err)
+	}
+
+	var events []string
+	for _, event := range announceEvents {
+		mark := "❌"
+		if cfg.Events[event.Key] {
+			mark = "✅"
+		}
+		events = append(events, mark+" "+event.Label)
+	}
+	return s.editResponse(sess, i, &discordgo.MessageEmbed{
+		Author: embedAuthor,
+		Title:  "Chain Announcements",
+		Color:  0x4CAF50,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Home", Value: cfg.HomeName, Inline: true},
+			{Name: "Posted To", Value: alertDestination(cfg.WebhookURL, "/announce"), Inline: true},
+			{Name: "Events", Value: strings.Join(events, "\n")},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Wormholes in the Tripwire chain connected to your home are announced as the map changes.",
+		},
+	}, nil)
+}
+
+// webhookName is the name the bot gives the webhooks it creates.
+const webhookName = "Short Circuit Bot"
+
+// alertWebhook returns the webhook URL alerts for a channel are posted to,
+// reusing the bot's saved webhook there while it still exists and creating one
+// otherwise. With no channel it returns "" for the bot's default alert webhook.
+// If neither works it returns an error embed instead.
+func (s *Service) alertWebhook(sess *discordgo.Session, channelID string) (string, *discordgo.MessageEmbed) {
+	if channelID == "" {
+		if s.announcer.HasDefaultWebhook() {
+			return "", nil
+		}
+		return "", &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: "This bot has no default alert channel. Please pick a channel to post in.",
+			Color:       0xff0000,
+		}
+	}
+	noPermission := &discordgo.MessageEmbed{
+		Author:      embedAuthor,
+		Description: fmt.Sprintf("I couldn't create a webhook in <#%s>. Please give me the Manage Webhooks permission there.", channelID),
+		Color:       0xff0000,
+	}
+
+	saved, err := s.store.GetChannelWebhook(channelID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		log.Printf("[BOT] WARN: could not load webhook for channel %s: %v", channelID, err)
+	}
+	if saved != nil {
+		existing, err := sess.ChannelWebhooks(channelID)
+		if err != nil {
+			log.Printf("[BOT] WARN: could not list webhooks in channel %s: %v", channelID, err)
+			return "", noPermission
+		}
+		for _, hook := range existing {
+			if hook.ID == saved.ID {
+				return saved.URL, nil
+			}
+		}
+	}
 
-	_, err := sess.ApplicationCommandBulkOverwrite(sess.State.User.ID, "", commands)
+	webhook, err := sess.WebhookCreate(channelID, webhookName, "")
 	if err != nil {
-		log.Fatalf("[BOT] FATAL: Could not register slash commands: %v", err)
+		log.Printf("[BOT] WARN: could not create webhook in channel %s: %v", channelID, err)
+		return "", noPermission
 	}
-	log.Println("[BOT] Slash commands registered.")
+	hook := ChannelWebhook{ID: webhook.ID, URL: discordgo.EndpointWebhookToken(webhook.ID, webhook.Token)}
+	if err := s.store.SaveChannelWebhook(channelID, hook); err != nil {
+		log.Printf("[BOT] WARN: could not save webhook for channel %s: %v", channelID, err)
+	}
+	return hook.URL, nil
 }
 
-// ---- interactionCreate (dispatcher) ----
-func (s *Service) interactionCreate(sess *discordgo.Session, i *discordgo.InteractionCreate) {
-	// Button clicks
-	if i.Type == discordgo.InteractionMessageComponent {
-		if err := s.handleButtonClick(sess, i); err != nil {
-			log.Printf("[BOT] ERROR handling button: %v", err)
+// releaseWebhook deletes the bot's webhook in a channel once none of the
+// guild's alert settings post through it any more.
+func (s *Service) releaseWebhook(sess *discordgo.Session, guildID, channelID string) {
+	if channelID == "" {
+		return
+	}
+	saved, err := s.store.GetChannelWebhook(channelID)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Printf("[BOT] WARN: could not load webhook for channel %s: %v", channelID, err)
 		}
 		return
 	}
-
-	// Slash command: route
-	if i.Type != discordgo.InteractionApplicationCommand || i.ApplicationCommandData().Name != "route" {
+	if cfg, err := s.store.GetAnnounceConfig(guildID); err == nil && cfg.WebhookURL == saved.URL {
 		return
 	}
-
-	if err := sess.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	}); err != nil {
-		log.Printf("[BOT] ERROR: Failed to defer interaction response: %v", err)
+	if cfg, err := s.store.GetHostileConfig(guildID); err == nil && cfg.WebhookURL == saved.URL {
 		return
 	}
 
-	// Process the route command
-	if err := s.handleRouteCommand(sess, i); err != nil {
-		log.Printf("[BOT] ERROR processing route: %v", err)
+	if err := sess.WebhookDelete(saved.ID); err != nil {
+		log.Printf("[BOT] WARN: could not delete webhook %s in channel %s: %v", saved.ID, channelID, err)
+	}
+	if err := s.store.DeleteChannelWebhook(channelID); err != nil {
+		log.Printf("[BOT] WARN: could not forget webhook for channel %s: %v", channelID, err)
 	}
 }
 
-// ---- button handler: Copy Route ----
-func (s *Service) handleButtonClick(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
-	data := i.MessageComponentData()
-	if data.CustomID != "copy_route_button" {
-		return nil
+// alertDestination describes where a guild's alerts are posted.
+func alertDestination(webhookURL, command string) string {
+	if webhookURL == "" {
+		return "The bot's alert channel"
 	}
+	return "A webhook created by " + command
+}
 
-	if len(i.Message.Embeds) == 0 {
-		return nil
+// ---- hostile handler: per-guild hostile activity alerts ----
+func (s *Service) handleHostileCommand(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if i.GuildID == "" {
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: "Hostile alerts are set per server. Use /hostile in a server channel.",
+			Color:       0xff0000,
+		}, nil)
 	}
-	embed := i.Message.Embeds[0]
-
-	// find Route Details field and pull bolded system names
-	var systems []string
-	for _, f := range embed.Fields {
-		if f.Name == "Route Details" {
-			for _, line := range strings.Split(f.Value, "\n") {
-				// the Name was formatted like: "🟢◦ **SystemName (0.9)** — ..." so extract between ** **
-				if strings.Contains(line, "**") {
-					parts := strings.Split(line, "**")
-					if len(parts) > 1 {
-						// parts[1] contains "SystemName (0.9)"; strip trailing sec paren to return raw name only if desired
-						namePart := parts[1]
-						// optionally trim "(sec)" from the name if present
-						if idx := strings.LastIndex(namePart, " ("); idx > 0 {
-							namePart = namePart[:idx]
-						}
-						systems = append(systems, strings.TrimSpace(namePart))
-					}
-				}
-			}
-			break
+
+	sub := i.ApplicationCommandData().Options[0]
+	opts := s.parseOptions(sub.Options)
+
+	if sub.Name != "show" && (i.Member == nil || i.Member.Permissions&discordgo.PermissionManageServer == 0) {
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: "You need the Manage Server permission to change hostile alerts.",
+			Color:       0xff0000,
+		}, nil)
+	}
+
+	previous, err := s.store.GetHostileConfig(i.GuildID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("could not load hostile alert settings: %w", err)
+	}
+
+	switch sub.Name {
+	case "off":
+		if err := s.store.DeleteHostileConfig(i.GuildID); err != nil {
+			return fmt.Errorf("could not delete hostile alert settings: %w", err)
+		}
+		if previous != nil {
+			s.releaseWebhook(sess, i.GuildID, previous.ChannelID)
+		}
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: "Hostile alerts are off for this server.",
+			Color:       0x4CAF50,
+		}, nil)
+
+	case "set":
+		if problems := s.checkSystemNames(opts, "home"); len(problems) > 0 {
+			return s.editResponse(sess, i, unresolvedSystemsEmbed(problems), nil)
+		}
+		homeID, err := s.esiClient.GetSystemID(opts["home"])
+		if err != nil {
+			return s.editResponse(sess, i, invalidSystemEmbed(opts["home"]), nil)
+		}
+
+		cfg := HostileAlertConfig{
+			HomeID:          homeID,
+			HomeName:        opts["home"],
+			MaxJumps:        defaultHostileJumps,
+			MinKills:        defaultHostileKills,
+			CooldownMinutes: defaultHostileCooldown,
+			ChannelID:       opts["channel"],
+		}
+		if v, err := strconv.Atoi(opts["jumps"]); err == nil {
+			cfg.MaxJumps = v
+		}
+		if v, err := strconv.Atoi(opts["kills"]); err == nil {
+			cfg.MinKills = v
+		}
+		if v, err := strconv.Atoi(opts["cooldown"]); err == nil {
+			cfg.CooldownMinutes = v
+		}
+
+		webhookURL, problem := s.alertWebhook(sess, opts["channel"])
+		if problem != nil {
+			return s.editResponse(sess, i, problem, nil)
+		}
+		cfg.WebhookURL = webhookURL
+
+		if err := s.store.SaveHostileConfig(i.GuildID, cfg); err != nil {
+			return fmt.Errorf("could not save hostile alert settings: %w", err)
+		}
+		if previous != nil && previous.ChannelID != cfg.ChannelID {
+			s.releaseWebhook(sess, i.GuildID, previous.ChannelID)
 		}
 	}
 
-	copyableText := strings.Join(systems, "\n")
-	return sess.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: "```\n" + copyableText + "\n```",
-			Flags:   discordgo.MessageFlagsEphemeral,
+	cfg, err := s.store.GetHostileConfig(i.GuildID)
+	if errors.Is(err, ErrNotFound) {
+		return s.editResponse(sess, i, &discordgo.MessageEmbed{
+			Author:      embedAuthor,
+			Description: "Hostile alerts are off for this server. Turn them on with /hostile set.",
+			Color:       0x4CAF50,
+		}, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("could not load hostile alert settings: %w", err)
+	}
+
+	return s.editResponse(sess, i, &discordgo.MessageEmbed{
+		Author: embedAuthor,
+		Title:  "Hostile Activity Alerts",
+		Color:  0x4CAF50,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Home", Value: cfg.HomeName, Inline: true},
+			{Name: "Range", Value: plural(cfg.MaxJumps, "jump"), Inline: true},
+			{Name: "Threshold", Value: fmt.Sprintf("%d+ kills/hr", cfg.MinKills), Inline: true},
+			{Name: "Cooldown", Value: fmt.Sprintf("%d min per system", cfg.CooldownMinutes), Inline: true},
+			{Name: "Posted To", Value: alertDestination(cfg.WebhookURL, "/hostile"), Inline: true},
 		},
-	})
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Systems in the Tripwire chain are checked against ESI kills every hour.",
+		},
+	}, nil)
 }
 
-// ---- main route handler ----
-func (s *Service) handleRouteCommand(sess *discordgo.Session, i *discordgo.InteractionCreate) error {
-	opts := s.parseOptions(i.ApplicationCommandData().Options)
-	startName, endName := opts["start"], opts["end"]
-	excludeInput := opts["exclude"]
-	preference := "shortest"
-	if v, ok := opts["preference"]; ok && v != "" {
-		preference = v
-	}
+// maxExcludedNames is the most excluded systems the route embed lists by name.
+const maxExcludedNames = 10
 
-	startID, err1 := s.esiClient.GetSystemID(startName)
-	endID, err2 := s.esiClient.GetSystemID(endName)
+// maxNameSuggestions is how many corrections are offered for an unknown system name.
+const maxNameSuggestions = 3
 
-	embedAuthor := &discordgo.MessageEmbedAuthor{
-		Name:    "Short Circuit Bot",
-		IconURL: "https://images.evetech.net/corporations/98330748/logo?size=64",
-	}
+// unresolvedSystem is a system name from a command option that matched no system.
+type unresolvedSystem struct {
+	Option      string
+	Input       string
+	Suggestions []string
+}
 
-	var embed *discordgo.MessageEmbed
-	var components []discordgo.MessageComponent
+// resolveSystemName finds the canonical name for user input, which may differ
+// in case or be an alias. If nothing matches it returns close suggestions instead.
+func (s *Service) resolveSystemName(input string) (string, []string, bool) {
+	if sys, ok := s.systems.Lookup(input); ok {
+		return sys.Name, nil, true
+	}
+	// ESI may know systems the local cache does not
+	if _, err := s.esiClient.GetSystemID(input); err == nil {
+		return input, nil, true
+	}
+	var suggestions []string
+	for _, sys := range s.systems.Closest(input, maxNameSuggestions) {
+		suggestions = append(suggestions, sys.Name)
+	}
+	return "", suggestions, false
+}
 
-	// invalid system names
-	if err1 != nil || err2 != nil {
-		embed = &discordgo.MessageEmbed{
-			Author:      embedAuthor,
-			Title:       "Error: Invalid System Name",
-			Description: "Sorry, I couldn't recognise one of those system names. Please check for typos.",
-			Color:       0xff0000,
+// checkSystemNames resolves the system names in the given options, rewriting
+// them to their canonical names. Comma-separated list options are checked
+// entry by entry. It returns every name that could not be resolved.
+func (s *Service) checkSystemNames(opts map[string]string, keys ...string) []unresolvedSystem {
+	var problems []unresolvedSystem
+	for _, key := range keys {
+		value := opts[key]
+		if value == "" {
+			continue
+		}
+		entries := []string{value}
+		if listOptions[key] {
+			entries = strings.Split(value, ",")
 		}
-	} else {
-		avoidList := s.buildAvoidList(excludeInput)
-		avoidList[30100000] = true // Zarzakh always excluded
-
-		// pathfinding (guarded by RLock)
-		s.graphMutex.RLock()
-		pathIDs := FindPreferredPath(s.universeGraph, startID, endID, s.esiClient, preference, avoidList)
-		s.graphMutex.RUnlock()
 
-		if pathIDs == nil {
-			embed = &discordgo.MessageEmbed{
-				Author:      embedAuthor,
-				Description: fmt.Sprintf("No route possible between **%s** and **%s**.", startName, endName),
-				Color:       0xff0000,
+		resolved := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			name, suggestions, ok := s.resolveSystemName(entry)
+			if !ok && key == "exclude" {
+				if area, err := s.areas.Resolve(entry); err != nil {
+					log.Printf("[BOT] WARN: %v", err)
+				} else if area != nil {
+					name, ok = area.Name, true
+				}
+			}
+			if !ok {
+				problems = append(problems, unresolvedSystem{Option: key, Input: entry, Suggestions: suggestions})
+				continue
 			}
+			resolved = append(resolved, name)
+		}
+		opts[key] = strings.Join(resolved, ", ")
+	}
+	return problems
+}
+
+// unresolvedSystemsEmbed names each unknown system, the option it came from and any suggested corrections.
+func unresolvedSystemsEmbed(problems []unresolvedSystem) *discordgo.MessageEmbed {
+	lines := make([]string, len(problems))
+	for n, p := range problems {
+		line := fmt.Sprintf("**%s**: couldn't find `%s`.", p.Option, p.Input)
+		if len(p.Suggestions) > 0 {
+			line += " Did you mean **" + strings.Join(p.Suggestions, "**, **") + "**?"
 		} else {
-			// load supporting data (file reads)
-			killMap := s.loadKills("system_kills.json")
-			sigMap, eolMap := s.loadTripwire("tripwire_data.json")
+			line += " No similar systems found."
+		}
+		lines[n] = line
+	}
+	return &discordgo.MessageEmbed{
+		Author:      embedAuthor,
+		Title:       "Error: Invalid System Name",
+		Description: strings.Join(lines, "\n"),
+		Color:       0xff0000,
+	}
+}
 
-			// fetch system intel concurrently
-			intelMap := s.fetchIntelForPath(pathIDs, killMap, sigMap, eolMap)
+// invalidSystemEmbed reports a system name that could not be resolved.
+func invalidSystemEmbed(name string) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{
+		Author:      embedAuthor,
+		Title:       "Error: Invalid System Name",
+		Description: fmt.Sprintf("Sorry, I couldn't recognise **%s**. Please check for typos.", name),
+		Color:       0xff0000,
+	}
+}
 
-			// format route lines (detailed style with small colored dots)
-			routeString := s.formatRouteString(pathIDs, intelMap)
+// renderRoute builds the embed and components for a view of a cached route.
+func (s *Service) renderRoute(rc *cachedRoute, view routeView) (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	if view.Index < 0 || view.Index >= len(rc.Paths) {
+		view.Index = 0
+	}
+	index := view.Index
+	pathIDs := rc.Paths[index]
 
-			jumpCount := len(pathIDs) - 1
-			embedColor := 0x4CAF50
-			if jumpCount > 10 {
-				embedColor = 0xFFC107
-			}
-			if jumpCount > 20 {
-				embedColor = 0xF44336
-			}
+	// load supporting data (in memory, plus the histories from the store)
+	killMap := s.currentKills()
+	jumpMap := s.killData.Jumps()
+	killHistory, err := s.store.KillHistory(pathIDs)
+	if err != nil {
+		log.Printf("[BOT] WARN: could not load kill history: %v", err)
+	}
+	jumpHistory, err := s.store.JumpHistory(pathIDs)
+	if err != nil {
+		log.Printf("[BOT] WARN: could not load jump history: %v", err)
+	}
+	sigMap, eolMap := s.loadTripwire()
 
-			// excluded system names for display
-			var excludedSysNames []string
-			for sysID := range avoidList {
-				if sysInfo, err := s.esiClient.GetSystemDetails(sysID); err == nil {
-					excludedSysNames = append(excludedSysNames, sysInfo.Name)
-				}
-			}
+	// fetch system intel concurrently
+	intelMap := s.fetchIntelForPath(pathIDs, killMap, jumpMap, killHistory, jumpHistory, sigMap, eolMap)
 
-			embed = &discordgo.MessageEmbed{
-				Author:    embedAuthor,
-				Title:     "Route Calculated",
-				Color:     embedColor,
-				Timestamp: time.Now().Format(time.RFC3339),
-				Fields: []*discordgo.MessageEmbedField{
-					{Name: "Start", Value: startName, Inline: true},
-					{Name: "End", Value: endName, Inline: true},
-					{Name: "Jumps", Value: fmt.Sprintf("%d", jumpCount), Inline: true},
-					{Name: "Route Details", Value: routeString},
-					{Name: "Excluded Systems", Value: strings.Join(excludedSysNames, ", ")},
-				},
-				Footer: &discordgo.MessageEmbedFooter{
-					Text: "Zarzakh is ALWAYS excluded. Kills are up to 60min old.",
-				},
-			}
+	// format route lines (detailed style with small colored dots), split into
+	// pages that each fit in one embed field
+	fullLines := s.formatRouteLines(pathIDs, intelMap, rc.Changed, false)
+	compactLines := s.formatRouteLines(pathIDs, intelMap, rc.Changed, true)
+	canCompact := len(compactLines) < len(fullLines)
+	if !canCompact {
+		view.Compact = false
+	}
+	routeLines := fullLines
+	if view.Compact {
+		routeLines = compactLines
+	}
+	pages := paginateLines(routeLines, maxEmbedFieldLength)
+	if view.Page < 0 || view.Page >= len(pages) {
+		view.Page = 0
+	}
+	detailsName := "Route Details"
+	if len(pages) > 1 {
+		detailsName = fmt.Sprintf("Route Details (page %d of %d)", view.Page+1, len(pages))
+	}
 
-			components = []discordgo.MessageComponent{
-				discordgo.ActionsRow{
-					Components: []discordgo.MessageComponent{
-						discordgo.Button{
-							Label:    "Copy Route",
-							Style:    discordgo.SecondaryButton,
-							Emoji:    &discordgo.ComponentEmoji{Name: "📋"},
-							CustomID: "copy_route_button",
-						},
-					},
+	jumpCount := len(pathIDs) - 1
+	embedColor := 0x4CAF50
+	if jumpCount > 10 {
+		embedColor = 0xFFC107
+	}
+	if jumpCount > 20 {
+		embedColor = 0xF44336
+	}
+
+	// excluded entries as given; regions and bands get a system count
+	excluded := "None"
+	if rc.Excluded != "" {
+		total := ""
+		if len(rc.Avoid) > maxExcludedNames {
+			total = fmt.Sprintf(" · %d systems in total", len(rc.Avoid))
+		}
+		limit := maxEmbedFieldLength - utf8.RuneCountInString(total)
+		excluded = fitLines(strings.Split(rc.Excluded, ", "), ", ", limit, "+%d more") + total
+	}
+	footer := "Kills and jumps are up to 60min old."
+	if s.killFeed != nil {
+		footer = "Kills cover the last hour, live from zKillboard. Jumps are up to 60min old."
+	}
+	if len(rc.AlwaysAvoided) > 0 {
+		footer = fmt.Sprintf("Always avoided in this server: %s. %s", strings.Join(rc.AlwaysAvoided, ", "), footer)
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Start", Value: rc.StartName, Inline: true},
+		{Name: "End", Value: rc.EndName, Inline: true},
+		{Name: "Jumps", Value: fmt.Sprintf("%d", jumpCount), Inline: true},
+	}
+	if rc.Ship != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Ship", Value: rc.Ship, Inline: true})
+	}
+	fields = append(fields,
+		&discordgo.MessageEmbedField{Name: detailsName, Value: pages[view.Page]},
+		&discordgo.MessageEmbedField{Name: "Excluded Systems", Value: excluded},
+	)
+	if len(rc.Legs) > 0 {
+		legLines := make([]string, len(rc.Legs))
+		for n, leg := range rc.Legs {
+			legLines[n] = fmt.Sprintf("%d. %s → %s: %s", n+1, leg.From, leg.To, plural(leg.Jumps, "jump"))
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Legs", Value: fitLines(legLines, "\n", maxEmbedFieldLength, "…and %d more")})
+	}
+	if len(rc.Skipped) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Skipped Wormholes", Value: s.formatSkippedJumps(rc.Skipped)})
+	}
+
+	title := "Route Calculated"
+	if rc.Title != "" {
+		title = rc.Title
+	}
+	if len(rc.Paths) > 1 {
+		title = fmt.Sprintf("%s (option %d of %d)", title, index+1, len(rc.Paths))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Author:      embedAuthor,
+		Title:       title,
+		Description: rc.Note,
+		Color:       embedColor,
+		Timestamp:   rc.CreatedAt.Format(time.RFC3339),
+		Fields:      fields,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: footer,
+		},
+	}
+
+	var components []discordgo.MessageComponent
+	if len(rc.Paths) > 1 {
+		menuOptions := make([]discordgo.SelectMenuOption, 0, len(rc.Paths))
+		for n, sum := range rc.Summaries {
+			menuOptions = append(menuOptions, discordgo.SelectMenuOption{
+				Label:       fmt.Sprintf("Route %d: %d jumps", n+1, sum.Jumps),
+				Description: fmt.Sprintf("Lowest sec %.1f · %d wormhole jumps", sum.LowestSec, sum.WormholeJumps),
+				Value:       strconv.Itoa(n),
+				Default:     n == index,
+			})
+		}
+		components = append(components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					MenuType:    discordgo.StringSelectMenu,
+					CustomID:    fmt.Sprintf("%s%s:%d", routeSelectPrefix, rc.ID, boolToInt(view.Compact)),
+					Placeholder: "Choose an alternative route",
+					Options:     menuOptions,
 				},
+			},
+		})
+	}
+	var navigation []discordgo.MessageComponent
+	if len(pages) > 1 {
+		prev, next := view, view
+		prev.Page--
+		next.Page++
+		navigation = append(navigation,
+			discordgo.Button{
+				Label:    "Previous",
+				Style:    discordgo.SecondaryButton,
+				Emoji:    &discordgo.ComponentEmoji{Name: "◀️"},
+				CustomID: routePageCustomID(rc.ID, prev),
+				Disabled: view.Page == 0,
+			},
+			discordgo.Button{
+				Label:    "Next",
+				Style:    discordgo.SecondaryButton,
+				Emoji:    &discordgo.ComponentEmoji{Name: "▶️"},
+				CustomID: routePageCustomID(rc.ID, next),
+				Disabled: view.Page == len(pages)-1,
+			},
+		)
+	}
+	if canCompact {
+		toggle := routeView{Index: view.Index, Compact: !view.Compact}
+		label := "Compact"
+		if view.Compact {
+			label = "Full List"
+		}
+		navigation = append(navigation, discordgo.Button{
+			Label:    label,
+			Style:    discordgo.SecondaryButton,
+			CustomID: routePageCustomID(rc.ID, toggle),
+		})
+	}
+	if len(navigation) > 0 {
+		components = append(components, discordgo.ActionsRow{Components: navigation})
+	}
+
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{
+			Label:    "Copy Route",
+			Style:    discordgo.SecondaryButton,
+			Emoji:    &discordgo.ComponentEmoji{Name: "📋"},
+			CustomID: copyRouteCustomID(rc.ID, index, copyFormatNames),
+		},
+	}
+	if rc.Rerunnable {
+		buttons = append(buttons, discordgo.Button{
+			Label:    "Re-run",
+			Style:    discordgo.SecondaryButton,
+			Emoji:    &discordgo.ComponentEmoji{Name: "🔄"},
+			CustomID: routeRerunPrefix + rc.ID,
+		})
+	}
+	if s.sso != nil {
+		buttons = append(buttons, discordgo.Button{
+			Label:    "Set Destination",
+			Style:    discordgo.PrimaryButton,
+			Emoji:    &discordgo.ComponentEmoji{Name: "🧭"},
+			CustomID: fmt.Sprintf("%s%s:%d", setDestinationPrefix, rc.ID, index),
+		})
+	}
+	components = append(components, discordgo.ActionsRow{Components: buttons})
+	return embed, components
+}
+
+// summarizeRoute counts the jumps, lowest security and wormhole jumps on a path.
+func (s *Service) summarizeRoute(graph *Graph, path []int) routeSummary {
+	sum := routeSummary{Jumps: len(path) - 1, LowestSec: 1.0}
+	for n, id := range path {
+		if sysInfo, err := s.esiClient.GetSystemDetails(id); err == nil && sysInfo.SecurityStatus < sum.LowestSec {
+			sum.LowestSec = sysInfo.SecurityStatus
+		}
+		if n > 0 {
+			if e, ok := graph.EdgeBetween(path[n-1], id); ok && e.IsWormhole() {
+				sum.WormholeJumps++
 			}
 		}
 	}
+	return sum
+}
+
+// ---- small helpers ----
 
+// editResponse fills in a deferred interaction response.
+func (s *Service) editResponse(sess *discordgo.Session, i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) error {
 	webhookEdit := discordgo.WebhookEdit{
 		Embeds:     &[]*discordgo.MessageEmbed{embed},
 		Components: &components,
@@ -270,7 +1945,53 @@ func (s *Service) handleRouteCommand(sess *discordgo.Session, i *discordgo.Inter
 	return err
 }
 
-// ---- small helpers ----
+// routeOptionsFrom turns the shared routing command options into RouteOptions.
+// The guild's always-avoid list is added to the exclude option.
+func (s *Service) routeOptionsFrom(guildID string, opts map[string]string) RouteOptions {
+	preference := "shortest"
+	if v, ok := opts["preference"]; ok && v != "" {
+		preference = v
+	}
+	minLife, _ := time.ParseDuration(opts["min_life"])
+	killWeight, ok := killWeights[opts["kill_weight"]]
+	if !ok {
+		killWeight = killWeights["medium"]
+	}
+
+	avoidList := s.buildAvoidList(strings.Join(s.avoidEntries(guildID, opts["exclude"]), ","))
+
+	return RouteOptions{
+		Preference:  preference,
+		Avoid:       avoidList,
+		Ship:        opts["ship"],
+		MinLifetime: minLife,
+		AvoidEOL:    opts["avoid_eol"] == "true",
+		Kills:       killActivity(s.currentKills()),
+		KillWeight:  killWeight,
+	}
+}
+
+// interactionUserID returns the ID of the user behind an interaction, in a guild or a DM.
+func interactionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// respondEphemeral replies to an interaction with a message only the invoking user can see.
+func (s *Service) respondEphemeral(sess *discordgo.Session, i *discordgo.InteractionCreate, content string) error {
+	return sess.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
 
 func (s *Service) parseOptions(options []*discordgo.ApplicationCommandInteractionDataOption) map[string]string {
 	result := map[string]string{}
@@ -278,7 +1999,11 @@ func (s *Service) parseOptions(options []*discordgo.ApplicationCommandInteractio
 		if opt == nil || opt.Name == "" {
 			continue
 		}
-		result[opt.Name] = opt.StringValue()
+		if opt.Type == discordgo.ApplicationCommandOptionString {
+			result[opt.Name] = opt.StringValue()
+		} else {
+			result[opt.Name] = fmt.Sprint(opt.Value)
+		}
 	}
 	return result
 }
@@ -293,6 +2018,17 @@ func (s *Service) buildAvoidList(excludeInput string) map[int]bool {
 		if sysName == "" {
 			continue
 		}
+		if sys, ok := s.systems.Lookup(sysName); ok {
+			avoid[sys.ID] = true
+			continue
+		}
+		// regions, constellations and security bands expand to all their systems
+		if area, err := s.areas.Resolve(sysName); err == nil && area != nil {
+			for _, id := range s.areas.Systems(area) {
+				avoid[id] = true
+			}
+			continue
+		}
 		if sysID, err := s.esiClient.GetSystemID(sysName); err == nil {
 			avoid[sysID] = true
 		}
@@ -300,34 +2036,78 @@ func (s *Service) buildAvoidList(excludeInput string) map[int]bool {
 	return avoid
 }
 
-func (s *Service) loadKills(path string) map[int]int {
-	killMap := make(map[int]int)
-	b, err := os.ReadFile(path)
-	if err != nil {
-		return killMap
+// currentKills returns ESI's latest hourly kills per system, with the live
+// zKillboard counts folded in when the feed is configured.
+func (s *Service) currentKills() map[int]EsiSystemKills {
+	killMap := s.killData.Kills()
+	if s.killFeed != nil {
+		mergeKills(killMap, s.killFeed.Counts())
+	}
+	return killMap
+}
+
+// mergeKills folds live kill counts into ESI's hourly ones. Both cover about
+// an hour, so each count takes the larger of the two rather than the sum.
+func mergeKills(killMap map[int]EsiSystemKills, live []EsiSystemKills) {
+	for _, l := range live {
+		k := killMap[l.SystemID]
+		k.SystemID = l.SystemID
+		k.ShipKills = max(k.ShipKills, l.ShipKills)
+		k.PodKills = max(k.PodKills, l.PodKills)
+		k.NpcKills = max(k.NpcKills, l.NpcKills)
+		killMap[l.SystemID] = k
+	}
+}
+
+// minTrendReadings is how many earlier hourly readings a system needs before
+// its latest count is compared against them.
+const minTrendReadings = 3
+
+// Smallest change from the average that counts as a trend, on top of a 50% swing.
+const (
+	jumpTrendMinChange = 10
+	killTrendMinChange = 3
+)
+
+// hourlyTrend compares a system's latest hourly count with its average over
+// the earlier readings in its history (the last reading is the latest count
+// itself). It returns "↑" or "↓" and the average when the two differ by at
+// least half and minChange, and "" otherwise or without enough history.
+func hourlyTrend(current int, history []HourlyReading, minChange int) (string, int) {
+	if len(history) < minTrendReadings+1 {
+		return "", 0
 	}
-	var all []EsiSystemKills
-	if err := json.Unmarshal(b, &all); err != nil {
-		log.Printf("[BOT] WARN: failed to parse %s: %v", path, err)
-		return killMap
+	earlier := history[:len(history)-1]
+	total := 0
+	for _, r := range earlier {
+		total += r.Count
 	}
-	for _, k := range all {
-		killMap[k.SystemID] = k.ShipKills
+	avg := total / len(earlier)
+	switch {
+	case 2*current >= 3*avg && current-avg >= minChange:
+		return "↑", avg
+	case 3*current <= 2*avg && avg-current >= minChange:
+		return "↓", avg
 	}
-	return killMap
+	return "", 0
 }
 
-func (s *Service) loadTripwire(path string) (map[int]string, map[int]time.Time) {
+// killActivity sums ship and pod kills per system for kill-weighted routing.
+func killActivity(killMap map[int]EsiSystemKills) map[int]int {
+	activity := make(map[int]int, len(killMap))
+	for id, k := range killMap {
+		activity[id] = k.ShipKills + k.PodKills
+	}
+	return activity
+}
+
+// loadTripwire maps systems to their wormhole signature and EOL time from the latest Tripwire poll.
+func (s *Service) loadTripwire() (map[int]string, map[int]time.Time) {
 	sigMap := make(map[int]string)
 	eolMap := make(map[int]time.Time)
 
-	b, err := os.ReadFile(path)
-	if err != nil {
-		return sigMap, eolMap
-	}
-	var td TripwireData
-	if err := json.Unmarshal(b, &td); err != nil {
-		log.Printf("[BOT] WARN: failed to parse %s: %v", path, err)
+	td := s.tripwire.Latest()
+	if td == nil {
 		return sigMap, eolMap
 	}
 	for _, sig := range td.Signatures {
@@ -353,9 +2133,15 @@ type SystemIntel struct {
 	SecDisplay  string
 	SignatureID string
 	EolInfo     string
+	KillTrend   string // "↑" or "↓" when kills are well off their recent average
+	KillAverage int    // kills/hr over the earlier readings, when KillTrend is set
+	Jumps       int
+	HasTraffic  bool   // false when there is no jump data for the system
+	JumpTrend   string // "↑" or "↓" when jumps are well off their recent average
+	JumpAverage int    // jumps/hr over the earlier readings, when JumpTrend is set
 }
 
-func (s *Service) fetchIntelForPath(path []int, killMap map[int]int, sigMap map[int]string, eolMap map[int]time.Time) map[int]SystemIntel {
+func (s *Service) fetchIntelForPath(path []int, killMap map[int]EsiSystemKills, jumpMap map[int]int, killHistory, jumpHistory map[int][]HourlyReading, sigMap map[int]string, eolMap map[int]time.Time) map[int]SystemIntel {
 	intelMap := make(map[int]SystemIntel)
 	var mu sync.Mutex
 	var wg sync.WaitGroup
@@ -370,9 +2156,15 @@ func (s *Service) fetchIntelForPath(path []int, killMap map[int]int, sigMap map[
 				intel.Name = si.Name
 				intel.SecDisplay = fmt.Sprintf("%.1f", si.SecurityStatus)
 			}
-			if k := killMap[sysID]; k != 0 {
+			if k := killMap[sysID].ShipKills; k != 0 {
 				intel.KillCount = k
 			}
+			intel.KillTrend, intel.KillAverage = hourlyTrend(intel.KillCount, killHistory[sysID], killTrendMinChange)
+			// ESI lists only k-space systems with jumps; the rest of k-space had none
+			if jumpMap != nil && sysID < 31000000 {
+				intel.Jumps, intel.HasTraffic = jumpMap[sysID], true
+				intel.JumpTrend, intel.JumpAverage = hourlyTrend(intel.Jumps, jumpHistory[sysID], jumpTrendMinChange)
+			}
 			if sig, ok := sigMap[sysID]; ok {
 				intel.SignatureID = sig
 			}
@@ -394,10 +2186,22 @@ func (s *Service) fetchIntelForPath(path []int, killMap map[int]int, sigMap map[
 	return intelMap
 }
 
-// formatRouteString builds the detailed embed field using small colored dots + tiny hollow dot suffix
-func (s *Service) formatRouteString(path []int, intelMap map[int]SystemIntel) string {
+// formatRouteLines builds the detailed route lines using small colored dots + tiny hollow dot suffix
+// Systems in highlight are marked as new to the route. In compact mode, runs of
+// uneventful high-sec systems between the start and end collapse into one line.
+func (s *Service) formatRouteLines(path []int, intelMap map[int]SystemIntel, highlight map[int]bool, compact bool) []string {
 	lines := make([]string, 0, len(path))
-	for _, sysID := range path {
+	var quiet []string // pending run of uneventful high-sec lines
+	flush := func() {
+		if len(quiet) >= compactMinRun {
+			lines = append(lines, fmt.Sprintf("… %d high-sec jumps …", len(quiet)))
+		} else {
+			lines = append(lines, quiet...)
+		}
+		quiet = quiet[:0]
+	}
+
+	for n, sysID := range path {
 		intel := intelMap[sysID]
 		secFloat, _ := strconv.ParseFloat(intel.SecDisplay, 64)
 
@@ -411,88 +2215,154 @@ func (s *Service) formatRouteString(path []int, intelMap map[int]SystemIntel) st
 
 		// Build line: marker, bold name (with sec), optional bits
 		line := fmt.Sprintf("%s **%s (%s)**", secMarker, intel.Name, intel.SecDisplay)
+		eventful := false
 		if intel.KillCount > 0 {
 			line += fmt.Sprintf(" — 🔥 %d kills", intel.KillCount)
+			eventful = true
+		}
+		if intel.KillTrend != "" {
+			if intel.KillCount == 0 {
+				line += " — 🔥 0 kills"
+			}
+			line += fmt.Sprintf(" %s (24h avg %d)", intel.KillTrend, intel.KillAverage)
+		}
+		// traffic alone does not stop compact mode collapsing a system
+		if intel.HasTraffic {
+			if intel.Jumps > 0 {
+				line += fmt.Sprintf(" — 🚀 %d jumps/hr", intel.Jumps)
+			} else {
+				line += " — 💤 no traffic"
+			}
+			if intel.JumpTrend != "" {
+				line += fmt.Sprintf(" %s (24h avg %d)", intel.JumpTrend, intel.JumpAverage)
+			}
 		}
 		if intel.SignatureID != "" {
 			line += fmt.Sprintf(" — WH: %s", intel.SignatureID)
+			eventful = true
 		}
 		if intel.EolInfo != "" {
 			line += fmt.Sprintf(" — %s", intel.EolInfo)
+			eventful = true
+		}
+		if highlight[sysID] {
+			line += " — 🆕"
+			eventful = true
+		}
+
+		endpoint := n == 0 || n == len(path)-1
+		if compact && !endpoint && !eventful && secFloat >= 0.5 {
+			quiet = append(quiet, line)
+			continue
 		}
+		flush()
 		lines = append(lines, line)
 	}
-	return strings.Join(lines, "\n")
+	flush()
+	return lines
 }
 
-// ---- Pathfinding unchanged in behaviour (minor safety fix in path recovery) ----
-func FindPreferredPath(graph map[int][]int, startID, endID int, esiClient *ESIClient, preference string, avoidList map[int]bool) []int {
-	costs := make(map[int]float64)
-	for id := range graph {
-		costs[id] = 1e9
-	}
-	costs[startID] = 0
-	parents := make(map[int]int)
-	pq := []int{startID}
-
-	for len(pq) > 0 {
-		var currentID int
-		var lowestCost = 1e10
-		var currentIndex = -1
-		for i, id := range pq {
-			if costs[id] < lowestCost {
-				lowestCost, currentID, currentIndex = costs[id], id, i
-			}
+// paginateLines groups lines into pages of at most limit characters each.
+// A single line longer than limit is cut short. Lengths are counted in runes,
+// as Discord counts them, so multi-byte names are never split.
+func paginateLines(lines []string, limit int) []string {
+	var pages []string
+	var page strings.Builder
+	pageLen := 0
+	for _, line := range lines {
+		line = truncateRunes(line, limit)
+		lineLen := utf8.RuneCountInString(line)
+		if pageLen > 0 && pageLen+1+lineLen > limit {
+			pages = append(pages, page.String())
+			page.Reset()
+			pageLen = 0
 		}
-		if currentIndex == -1 {
-			break
+		if pageLen > 0 {
+			page.WriteString("\n")
+			pageLen++
 		}
+		page.WriteString(line)
+		pageLen += lineLen
+	}
+	if page.Len() > 0 || len(pages) == 0 {
+		pages = append(pages, page.String())
+	}
+	return pages
+}
 
-		// pop current
-		pq = append(pq[:currentIndex], pq[currentIndex+1:]...)
+// truncateRunes cuts s to at most limit characters, ending it with "…" when shortened.
+func truncateRunes(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit-1]) + "…"
+}
 
-		if currentID == endID {
-			// Reconstruct path safely (stop if parent missing)
-			path := []int{}
-			at := endID
-			for {
-				path = append([]int{at}, path...)
-				if at == startID {
-					break
-				}
-				parent, ok := parents[at]
-				if !ok {
-					// Broken parent chain: abort
-					return nil
-				}
-				at = parent
+// fitLines joins as many items as fit in limit characters, ending with more
+// (a format taking the number left out) when some do not fit.
+func fitLines(items []string, sep string, limit int, more string) string {
+	var b strings.Builder
+	length := 0
+	sepLen := utf8.RuneCountInString(sep)
+	for n, item := range items {
+		item = truncateRunes(item, limit)
+		add := utf8.RuneCountInString(item)
+		if n > 0 {
+			add += sepLen
+		}
+		// Leave room to say how many of the rest were left out.
+		reserve := 0
+		if rest := len(items) - n - 1; rest > 0 {
+			reserve = sepLen + utf8.RuneCountInString(fmt.Sprintf(more, rest))
+		}
+		if length+add+reserve > limit {
+			if n > 0 {
+				b.WriteString(sep)
 			}
-			return path
+			b.WriteString(fmt.Sprintf(more, len(items)-n))
+			break
+		}
+		if n > 0 {
+			b.WriteString(sep)
 		}
+		b.WriteString(item)
+		length += add
+	}
+	return b.String()
+}
 
-		for _, neighborID := range graph[currentID] {
-			if avoidList[neighborID] {
-				continue
-			}
+// boolToInt encodes a flag for a component CustomID.
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
 
-			cost := 1.0
-			if preference != "shortest" {
-				if sysInfo, err := esiClient.GetSystemDetails(neighborID); err == nil {
-					isHighSec := sysInfo.SecurityStatus >= 0.5
-					if preference == "safer" && !isHighSec {
-						cost += 100.0
-					} else if preference == "unsafe" && isHighSec {
-						cost += 100.0
-					}
-				}
-			}
+// maxSkippedLines caps how many skipped wormholes are listed in the route embed.
+const maxSkippedLines = 8
 
-			newCost := costs[currentID] + cost
-			if newCost < costs[neighborID] {
-				costs[neighborID], parents[neighborID] = newCost, currentID
-				pq = append(pq, neighborID)
-			}
+// formatSkippedJumps lists the wormholes a route search left out, one per line with the reason.
+func (s *Service) formatSkippedJumps(skipped []SkippedJump) string {
+	lines := make([]string, 0, maxSkippedLines+1)
+	for i, sj := range skipped {
+		if i == maxSkippedLines {
+			lines = append(lines, fmt.Sprintf("…and %d more", len(skipped)-maxSkippedLines))
+			break
 		}
+		line := fmt.Sprintf("%s ⇄ %s", s.systemName(sj.Edge.FromID), s.systemName(sj.Edge.ToID))
+		if sj.Edge.FromSignature != "" {
+			line += fmt.Sprintf(" (%s)", sj.Edge.FromSignature)
+		}
+		lines = append(lines, line+" — "+sj.Reason)
 	}
-	return nil
+	return strings.Join(lines, "\n")
+}
+
+// systemName returns a system's name, or its ID if the name is unknown.
+func (s *Service) systemName(systemID int) string {
+	if sysInfo, err := s.esiClient.GetSystemDetails(systemID); err == nil {
+		return sysInfo.Name
+	}
+	return fmt.Sprintf("Unknown (%d)", systemID)
 }
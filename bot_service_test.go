@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFitLines(t *testing.T) {
+	lines := make([]string, 40)
+	for n := range lines {
+		lines[n] = fmt.Sprintf("%d. Jita → Ämarr: %d jumps", n+1, n)
+	}
+
+	if got := fitLines(lines[:3], "\n", 1024, "…and %d more"); got != strings.Join(lines[:3], "\n") {
+		t.Errorf("short list changed: %q", got)
+	}
+
+	got := fitLines(lines, "\n", 200, "…and %d more")
+	if n := utf8.RuneCountInString(got); n > 200 {
+		t.Errorf("got %d characters, want at most 200", n)
+	}
+	kept := strings.Split(got, "\n")
+	if want := fmt.Sprintf("…and %d more", len(lines)-len(kept)+1); kept[len(kept)-1] != want {
+		t.Errorf("last line %q, want %q", kept[len(kept)-1], want)
+	}
+
+	if got := fitLines([]string{strings.Repeat("x", 50)}, ", ", 10, "+%d more"); utf8.RuneCountInString(got) != 10 {
+		t.Errorf("single long item not cut to the limit: %q", got)
+	}
+}
+
+func TestHourlyTrend(t *testing.T) {
+	readings := func(counts ...int) []HourlyReading {
+		history := make([]HourlyReading, len(counts))
+		for n, c := range counts {
+			history[n] = HourlyReading{Count: c}
+		}
+		return history
+	}
+	tests := []struct {
+		current   int
+		history   []HourlyReading
+		minChange int
+		want      string
+	}{
+		{current: 9, history: readings(2, 2, 2, 9), minChange: killTrendMinChange, want: "↑"},
+		{current: 3, history: readings(2, 2, 2, 3), minChange: killTrendMinChange, want: ""},
+		{current: 0, history: readings(6, 6, 6, 0), minChange: killTrendMinChange, want: "↓"},
+		{current: 9, history: readings(2, 2, 9), minChange: killTrendMinChange, want: ""}, // too little history
+		{current: 15, history: readings(8, 8, 8, 15), minChange: jumpTrendMinChange, want: ""},
+	}
+	for _, tt := range tests {
+		if got, _ := hourlyTrend(tt.current, tt.history, tt.minChange); got != tt.want {
+			t.Errorf("hourlyTrend(%d, %v, %d) = %q, want %q", tt.current, tt.history, tt.minChange, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// copyRoutePrefix prefixes the CustomID of the Copy Route buttons.
+const copyRoutePrefix = "copy_route:"
+
+// Copy Route output formats.
+const (
+	copyFormatNames  = "names"
+	copyFormatInGame = "ingame"
+	copyFormatIDs    = "ids"
+)
+
+// copyFormats are the formats offered on the Copy Route reply, in button order.
+var copyFormats = []struct {
+	Key   string
+	Label string
+}{
+	{copyFormatNames, "System Names"},
+	{copyFormatInGame, "In-Game Links"},
+	{copyFormatIDs, "System IDs (CSV)"},
+}
+
+// maxCopyLength keeps the copy text, fences included, inside Discord's 2000 character message limit.
+const maxCopyLength = 1900
+
+func copyRouteCustomID(routeID string, index int, format string) string {
+	return fmt.Sprintf("%s%s:%d:%s", copyRoutePrefix, routeID, index, format)
+}
+
+// formatCopyText renders a path for pasting elsewhere, wrapped in a code block.
+func (s *Service) formatCopyText(path []int, format string) string {
+	if format == copyFormatIDs {
+		ids := make([]string, len(path))
+		for n, id := range path {
+			ids[n] = strconv.Itoa(id)
+		}
+		return "```\n" + truncateCopyText(ids, ",") + "\n```"
+	}
+
+	lines := make([]string, len(path))
+	for n, id := range path {
+		name := s.systemName(id)
+		if format == copyFormatInGame {
+			// EVE chat link markup; pasted into an in-game channel or note it becomes a clickable system link.
+			lines[n] = fmt.Sprintf("<url=showinfo:5//%d>%s</url>", id, name)
+		} else {
+			lines[n] = name
+		}
+	}
+	return "```\n" + truncateCopyText(lines, "\n") + "\n```"
+}
+
+// truncateCopyText joins items with sep, cutting off whole items once the text
+// would pass maxCopyLength and noting how many were left out.
+func truncateCopyText(items []string, sep string) string {
+	var b strings.Builder
+	for n, item := range items {
+		if b.Len()+len(sep)+len(item) > maxCopyLength-20 {
+			fmt.Fprintf(&b, "\n… %d more", len(items)-n)
+			break
+		}
+		if n > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(item)
+	}
+	return b.String()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Direct access to public ESI endpoints the ESIClient does not cover.
+
+const (
+	esiBaseURL   = "https://esi.evetech.net/latest"
+	esiUserAgent = "ShortCircuitBot/0.1"
+)
+
+var esiHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// esiGetJSON performs a GET against a public ESI endpoint and decodes the JSON response.
+func esiGetJSON(endpoint string, target interface{}) error {
+	req, err := http.NewRequest("GET", esiBaseURL+endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return doESIRequest(req, target)
+}
+
+// esiPostJSON performs a POST with a JSON body against a public ESI endpoint and decodes the JSON response.
+func esiPostJSON(endpoint string, body interface{}, target interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request body: %w", err)
+	}
+	req, err := http.NewRequest("POST", esiBaseURL+endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doESIRequest(req, target)
+}
+
+// esiAuthGetJSON performs an authenticated GET and decodes the JSON response.
+// A 403 is reported as ErrMissingScope.
+func esiAuthGetJSON(endpoint, accessToken string, target interface{}) error {
+	req, err := http.NewRequest("GET", esiBaseURL+endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return doESIRequest(req, target)
+}
+
+// esiAuthPost performs an authenticated POST with no body, as used by the ESI UI endpoints.
+func esiAuthPost(endpoint, accessToken string) error {
+	req, err := http.NewRequest("POST", esiBaseURL+endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("User-Agent", esiUserAgent)
+
+	resp, err := esiHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("esi returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func doESIRequest(req *http.Request, target interface{}) error {
+	req.Header.Set("User-Agent", esiUserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := esiHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusForbidden && req.Header.Get("Authorization") != "" {
+		return ErrMissingScope
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("esi returned non-200 status: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode json response: %w", err)
+	}
+	return nil
+}
@@ -44,29 +44,80 @@ func (c *EveScoutClient) makeRequest(endpoint string, target interface{}) error
 	return nil
 }
 
+// EveScoutSignature is a connection from the EVE-Scout v2 signatures endpoint.
+// The out side is the hub (Thera or Turnur), the in side is where it leads.
+type EveScoutSignature struct {
+	ID           string    `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	WhType       string    `json:"wh_type"`
+	MaxShipSize  string    `json:"max_ship_size"` // "small", "medium", "large", "xlarge" or "capital"
+	ExpiresAt    time.Time `json:"expires_at"`
+	OutSystemID  int       `json:"out_system_id"`
+	OutSignature string    `json:"out_signature"`
+	InSystemID   int       `json:"in_system_id"`
+	InSignature  string    `json:"in_signature"`
+}
+
 // GetTheraConnections fetches all public wormhole connections for Thera.
-func (c *EveScoutClient) GetTheraConnections() ([]TheraConnection, error) {
-	var connections []TheraConnection
+func (c *EveScoutClient) GetTheraConnections() ([]EveScoutSignature, error) {
+	var connections []EveScoutSignature
 	endpoint := "/public/signatures?system_name=thera"
 	err := c.makeRequest(endpoint, &connections)
 	return connections, err
 }
 
+// GetTurnurConnections fetches all public wormhole connections for Turnur.
+func (c *EveScoutClient) GetTurnurConnections() ([]EveScoutSignature, error) {
+	var connections []EveScoutSignature
+	endpoint := "/public/signatures?system_name=turnur"
+	err := c.makeRequest(endpoint, &connections)
+	return connections, err
+}
+
+const (
+	theraSystemID  = 31000005
+	turnurSystemID = 30002086
+)
+
+// hubEdges converts EVE-Scout connections into wormhole edges out of a hub system.
+func hubEdges(hubID int, kind EdgeKind, connections []EveScoutSignature, seen time.Time) []Edge {
+	edges := make([]Edge, 0, len(connections))
+	for _, conn := range connections {
+		if conn.InSystemID == 0 {
+			continue
+		}
+		edges = append(edges, Edge{
+			FromID:        hubID,
+			ToID:          conn.InSystemID,
+			Kind:          kind,
+			Source:        SourceEveScout,
+			FromSignature: conn.OutSignature,
+			ToSignature:   conn.InSignature,
+			WormholeType:  conn.WhType,
+			MaxShipSize:   conn.MaxShipSize,
+			CreatedAt:     conn.CreatedAt,
+			UpdatedAt:     conn.UpdatedAt,
+			ExpiresAt:     conn.ExpiresAt,
+			LastSeen:      seen,
+		})
+	}
+	return edges
+}
+
 // --- Thera Updater Service ---
 
 // TheraUpdater manages the background fetching of Thera connections.
 type TheraUpdater struct {
 	eveScoutClient *EveScoutClient
-	universeGraph  map[int][]int
-	graphMutex     *sync.RWMutex
+	graphStore     *GraphStore
 }
 
 // NewTheraUpdater creates a new Thera data updater service.
-func NewTheraUpdater(client *EveScoutClient, graph map[int][]int, mutex *sync.RWMutex) *TheraUpdater {
+func NewTheraUpdater(client *EveScoutClient, graphStore *GraphStore) *TheraUpdater {
 	return &TheraUpdater{
 		eveScoutClient: client,
-		universeGraph:  graph,
-		graphMutex:     mutex,
+		graphStore:     graphStore,
 	}
 }
 
@@ -91,29 +142,24 @@ func (u *TheraUpdater) Start(wg *sync.WaitGroup, quit chan struct{}) {
 	}
 }
 
-// updateGraph fetches Thera connections and merges them into the main graph.
+// updateGraph fetches Thera and Turnur connections and swaps them in for the
+// previous set, so collapsed holes drop out of the graph on the next cycle.
 func (u *TheraUpdater) updateGraph() {
-	log.Println("[THERA UPDATER] Fetching Thera connections from EVE-Scout...")
+	log.Println("[THERA UPDATER] Fetching Thera and Turnur connections from EVE-Scout...")
 	theraConnections, err := u.eveScoutClient.GetTheraConnections()
 	if err != nil {
 		log.Printf("[THERA UPDATER] ERROR: Failed to fetch Thera data: %v", err)
 		return
 	}
-
-	u.graphMutex.Lock()
-	defer u.graphMutex.Unlock()
-
-	const theraSystemID = 31000005
-	theraAdded := 0
-	for _, conn := range theraConnections {
-		if conn.DestinationSystem != nil {
-			destID := conn.DestinationSystem.ID
-			u.universeGraph[theraSystemID] = append(u.universeGraph[theraSystemID], destID)
-			u.universeGraph[destID] = append(u.universeGraph[destID], theraSystemID)
-			theraAdded++
-		}
+	turnurConnections, err := u.eveScoutClient.GetTurnurConnections()
+	if err != nil {
+		log.Printf("[THERA UPDATER] WARN: Failed to fetch Turnur data: %v", err)
 	}
 
-	DeduplicateNeighbors(u.universeGraph)
-	log.Printf("[THERA UPDATER] ✅ Merged %d Thera connections into the graph.", theraAdded)
+	now := time.Now()
+	edges := hubEdges(theraSystemID, EdgeThera, theraConnections, now)
+	edges = append(edges, hubEdges(turnurSystemID, EdgeTurnur, turnurConnections, now)...)
+
+	expired := u.graphStore.ReplaceSource(SourceEveScout, edges)
+	log.Printf("[THERA UPDATER] ✅ Published %d Thera/Turnur connections (%d expired connections dropped).", len(edges), expired)
 }
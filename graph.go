@@ -0,0 +1,252 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EdgeKind identifies what a jump between two systems actually is.
+type EdgeKind int
+
+const (
+	EdgeStargate EdgeKind = iota
+	EdgeWormhole
+	EdgeThera
+	EdgeTurnur
+)
+
+func (k EdgeKind) String() string {
+	switch k {
+	case EdgeStargate:
+		return "stargate"
+	case EdgeWormhole:
+		return "wormhole"
+	case EdgeThera:
+		return "thera"
+	case EdgeTurnur:
+		return "turnur"
+	}
+	return "unknown"
+}
+
+// Sources an edge can come from.
+const (
+	SourceStatic   = "sde"
+	SourceTripwire = "tripwire"
+	SourceEveScout = "eve-scout"
+)
+
+// Edge is a directed jump from one system to another. Wormhole edges carry the
+// signature on each side plus the mass/life status reported by the mapper.
+type Edge struct {
+	FromID        int
+	ToID          int
+	Kind          EdgeKind
+	Source        string
+	FromSignature string // signature ID in FromID, e.g. "ABC-123"
+	ToSignature   string // signature ID in ToID
+	WormholeType  string // e.g. "K346"; empty when unknown
+	MaxShipSize   string // EVE-Scout size class, e.g. "large"; empty when unknown
+	Mass          string // "stable", "destab" or "critical"
+	Life          string // "stable" or "critical"
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	ExpiresAt     time.Time // zero when the connection does not expire
+	LastSeen      time.Time // when the source last reported this connection
+}
+
+// Expired reports whether the connection has passed its end of life.
+func (e Edge) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt)
+}
+
+// IsWormhole reports whether the jump goes through any kind of wormhole.
+func (e Edge) IsWormhole() bool {
+	return e.Kind != EdgeStargate
+}
+
+// Reverse returns the same connection as seen from the other side.
+func (e Edge) Reverse() Edge {
+	r := e
+	r.FromID, r.ToID = e.ToID, e.FromID
+	r.FromSignature, r.ToSignature = e.ToSignature, e.FromSignature
+	return r
+}
+
+// Graph is an adjacency list of typed edges keyed by solar system ID. Once a
+// graph has been published by a GraphStore it must not be modified.
+type Graph struct {
+	edges     map[int][]Edge
+	landmarks *LandmarkHeuristic // set for published snapshots; nil otherwise
+}
+
+// NewGraph creates an empty graph.
+func NewGraph() *Graph {
+	return &Graph{edges: make(map[int][]Edge)}
+}
+
+// Clone returns a deep copy of the graph's edges.
+func (g *Graph) Clone() *Graph {
+	c := &Graph{edges: make(map[int][]Edge, len(g.edges))}
+	for id, edges := range g.edges {
+		c.edges[id] = append(make([]Edge, 0, len(edges)), edges...)
+	}
+	return c
+}
+
+// AddConnection adds a two-way connection. Wormholes are told apart by their
+// signature, so two holes between the same pair of systems are both kept. An
+// existing edge of the same kind, pair and signature is replaced, unless it has
+// no signature and outlives the new one.
+func (g *Graph) AddConnection(e Edge) {
+	g.addEdge(e)
+	g.addEdge(e.Reverse())
+}
+
+func (g *Graph) addEdge(e Edge) {
+	for i, existing := range g.edges[e.FromID] {
+		if existing.ToID != e.ToID || existing.Kind != e.Kind || existing.FromSignature != e.FromSignature {
+			continue
+		}
+		if e.FromSignature == "" && existing.outlives(e) {
+			return
+		}
+		g.edges[e.FromID][i] = e
+		return
+	}
+	g.edges[e.FromID] = append(g.edges[e.FromID], e)
+}
+
+// outlives reports whether e expires later than other. A connection that never
+// expires outlives any that does.
+func (e Edge) outlives(other Edge) bool {
+	if e.ExpiresAt.IsZero() || other.ExpiresAt.IsZero() {
+		return e.ExpiresAt.IsZero() && !other.ExpiresAt.IsZero()
+	}
+	return e.ExpiresAt.After(other.ExpiresAt)
+}
+
+// Neighbors returns the outgoing edges of a system.
+func (g *Graph) Neighbors(systemID int) []Edge {
+	return g.edges[systemID]
+}
+
+// EdgeBetween returns the edge used to jump from one system to the other,
+// preferring a stargate when both a gate and a wormhole connect them, and the
+// longest-lived wormhole when several do.
+func (g *Graph) EdgeBetween(fromID, toID int) (Edge, bool) {
+	var found Edge
+	ok := false
+	for _, e := range g.edges[fromID] {
+		if e.ToID != toID {
+			continue
+		}
+		if !ok || e.Kind == EdgeStargate || (found.Kind != EdgeStargate && e.outlives(found)) {
+			found, ok = e, true
+		}
+	}
+	return found, ok
+}
+
+// HasSystem reports whether the system has any connections.
+func (g *Graph) HasSystem(systemID int) bool {
+	_, ok := g.edges[systemID]
+	return ok
+}
+
+// Len returns the number of systems in the graph.
+func (g *Graph) Len() int {
+	return len(g.edges)
+}
+
+// SystemIDs returns every system in the graph, in no particular order.
+func (g *Graph) SystemIDs() []int {
+	ids := make([]int, 0, len(g.edges))
+	for id := range g.edges {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// ---- snapshot store ----
+
+// landmarkCount is how many A* landmarks are precomputed for each snapshot.
+const landmarkCount = 8
+
+// GraphStore publishes immutable graph snapshots via an atomic pointer swap.
+// Each updater owns an overlay of dynamic connections keyed by source; replacing
+// an overlay builds a fresh snapshot from the static stargate base plus every
+// current overlay. Readers never take a lock.
+type GraphStore struct {
+	base     *Graph
+	mu       sync.Mutex // serialises writers only
+	overlays map[string][]Edge
+	current  atomic.Pointer[Graph]
+}
+
+// NewGraphStore creates a store around the static stargate graph and publishes it.
+func NewGraphStore(base *Graph) *GraphStore {
+	s := &GraphStore{
+		base:     base,
+		overlays: make(map[string][]Edge),
+	}
+	s.rebuild(time.Now())
+	return s
+}
+
+// Snapshot returns the current graph. The result must be treated as read-only.
+func (s *GraphStore) Snapshot() *Graph {
+	return s.current.Load()
+}
+
+// ReplaceSource swaps in a new set of connections for source and publishes a new
+// snapshot. Connections already past their end of life are left out.
+// Returns the number of expired connections dropped across all overlays.
+func (s *GraphStore) ReplaceSource(source string, connections []Edge) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.overlays[source] = connections
+	return s.rebuild(time.Now())
+}
+
+// Prune rebuilds the snapshot so connections that expired since the last
+// update are removed. Returns the number of connections dropped; when nothing
+// has expired the current snapshot is kept.
+func (s *GraphStore) Prune() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, connections := range s.overlays {
+		for _, e := range connections {
+			if e.Expired(now) {
+				return s.rebuild(now)
+			}
+		}
+	}
+	return 0
+}
+
+// rebuild must be called with mu held (or before the store is shared).
+func (s *GraphStore) rebuild(now time.Time) int {
+	g := s.base.Clone()
+	expired := 0
+	for source, connections := range s.overlays {
+		live := connections[:0:0]
+		for _, e := range connections {
+			if e.Expired(now) {
+				expired++
+				continue
+			}
+			e.Source = source
+			live = append(live, e)
+			g.AddConnection(e)
+		}
+		s.overlays[source] = live
+	}
+	g.landmarks = NewLandmarkHeuristic(g, landmarkCount)
+	s.current.Store(g)
+	return expired
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Defaults for /hostile set.
+const (
+	defaultHostileJumps    = 3
+	defaultHostileKills    = 5
+	defaultHostileCooldown = 60 // minutes
+)
+
+// Lower bounds of the /hostile set options; Discord takes them by pointer.
+var (
+	hostileMinJumps    = 0.0
+	hostileMinKills    = 1.0
+	hostileMinCooldown = 0.0
+)
+
+// HostileAlertConfig is a guild's hostile activity alert settings.
+type HostileAlertConfig struct {
+	HomeID          int    `json:"home_id"`
+	HomeName        string `json:"home_name"`
+	MaxJumps        int    `json:"max_jumps"`            // jumps from home to watch, by stargate or wormhole
+	MinKills        int    `json:"min_kills"`            // ship and pod kills in the last hour that trigger an alert
+	CooldownMinutes int    `json:"cooldown_minutes"`     // quiet time per system after an alert
+	ChannelID       string `json:"channel_id,omitempty"` // channel the webhook posts to; empty for the default
+	WebhookURL      string `json:"webhook_url"`          // empty means the bot's default alert webhook
+}
+
+// hostileSystem is a chain system whose kills crossed a guild's threshold.
+type hostileSystem struct {
+	SystemID int
+	Jumps    int
+	Kills    EsiSystemKills
+}
+
+// HandleKills subscribes to the KillDataUpdater and, when configured, the live
+// KillFeed. It checks each guild's chain against the new kill counts and alerts
+// on systems over the threshold.
+func (a *Announcer) HandleKills(kills []EsiSystemKills) {
+	configs, err := a.store.HostileConfigs()
+	if err != nil {
+		log.Printf("[ANNOUNCER] ERROR: could not load hostile alert settings: %v", err)
+		return
+	}
+	if len(configs) == 0 {
+		return
+	}
+
+	killMap := make(map[int]EsiSystemKills, len(kills))
+	for _, k := range kills {
+		killMap[k.SystemID] = k
+	}
+
+	a.mu.Lock()
+	data := a.previous
+	a.mu.Unlock()
+
+	go func() {
+		graph := a.graphStore.Snapshot()
+		for guildID, cfg := range configs {
+			now := time.Now()
+			hostile := a.hostileSystems(guildID, cfg, tripwireChain(data, cfg.HomeID), jumpDistances(graph, cfg.HomeID), killMap, now)
+			if len(hostile) == 0 {
+				continue
+			}
+			embeds := make([]*discordgo.MessageEmbed, len(hostile))
+			for n, h := range hostile {
+				embeds[n] = a.hostileEmbed(cfg, h)
+			}
+			if err := a.Post(a.hostileWebhookFor(cfg), embeds); err != nil {
+				log.Printf("[ANNOUNCER] ERROR: posting hostile alert to guild %s: %v", guildID, err)
+				continue
+			}
+			a.startCooldowns(guildID, hostile, now)
+		}
+	}()
+}
+
+// hostileSystems returns the chain systems within range of home whose kills
+// reach the guild's threshold and are not cooling down, nearest first. Range
+// is counted in jumps through the whole graph, stargates included.
+func (a *Announcer) hostileSystems(guildID string, cfg HostileAlertConfig, chain, distances map[int]int, killMap map[int]EsiSystemKills, now time.Time) []hostileSystem {
+	cooldown := time.Duration(cfg.CooldownMinutes) * time.Minute
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var hostile []hostileSystem
+	for systemID := range chain {
+		jumps, ok := distances[systemID]
+		if !ok || jumps > cfg.MaxJumps {
+			continue
+		}
+		k := killMap[systemID]
+		if k.ShipKills+k.PodKills < cfg.MinKills {
+			continue
+		}
+		if last, ok := a.lastAlert[hostileKey(guildID, systemID)]; ok && now.Sub(last) < cooldown {
+			continue
+		}
+		hostile = append(hostile, hostileSystem{SystemID: systemID, Jumps: jumps, Kills: k})
+	}
+	sort.Slice(hostile, func(x, y int) bool {
+		if hostile[x].Jumps != hostile[y].Jumps {
+			return hostile[x].Jumps < hostile[y].Jumps
+		}
+		return hostile[x].SystemID < hostile[y].SystemID
+	})
+	return hostile
+}
+
+// startCooldowns records an alert for each system once it has been posted.
+func (a *Announcer) startCooldowns(guildID string, hostile []hostileSystem, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, h := range hostile {
+		a.lastAlert[hostileKey(guildID, h.SystemID)] = now
+	}
+}
+
+// hostileKey identifies a system's cooldown within a guild.
+func hostileKey(guildID string, systemID int) string {
+	return fmt.Sprintf("%s:%d", guildID, systemID)
+}
+
+func (a *Announcer) hostileEmbed(cfg HostileAlertConfig, h hostileSystem) *discordgo.MessageEmbed {
+	distance := "your home system"
+	if h.Jumps > 0 {
+		distance = fmt.Sprintf("%s from %s", plural(h.Jumps, "jump"), cfg.HomeName)
+	}
+	return &discordgo.MessageEmbed{
+		Author:      embedAuthor,
+		Title:       "🚨 Hostile Activity in the Chain",
+		Description: fmt.Sprintf("**%s** is %s.", a.systemName(h.SystemID), distance),
+		Color:       0xff0000,
+		Timestamp:   time.Now().Format(time.RFC3339),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Ship Kills", Value: fmt.Sprint(h.Kills.ShipKills), Inline: true},
+			{Name: "Pod Kills", Value: fmt.Sprint(h.Kills.PodKills), Inline: true},
+			{Name: "NPC Kills", Value: fmt.Sprint(h.Kills.NpcKills), Inline: true},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Kills in the last hour. Alerts at %d+ kills within %s of home.", cfg.MinKills, plural(cfg.MaxJumps, "jump")),
+		},
+	}
+}
+
+func (a *Announcer) hostileWebhookFor(cfg HostileAlertConfig) string {
+	if cfg.WebhookURL != "" {
+		return cfg.WebhookURL
+	}
+	return a.defaultWebhook
+}
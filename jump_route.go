@@ -0,0 +1,149 @@
+package main
+
+import (
+	"container/heap"
+	"errors"
+	"time"
+)
+
+// jumpShip describes a jump-drive hull class for /jumproute.
+type jumpShip struct {
+	Name         string
+	BaseRange    float64 // light years with Jump Drive Calibration 0
+	FatigueBonus float64 // share of the distance ignored for fatigue, e.g. 0.9 for jump freighters
+}
+
+// jumpShips maps the /jumproute ship option to its hull class.
+var jumpShips = map[string]jumpShip{
+	"capital":        {Name: "Carrier / Dreadnought / FAX", BaseRange: 3.5},
+	"supercapital":   {Name: "Supercarrier / Titan", BaseRange: 3.0},
+	"jump-freighter": {Name: "Jump Freighter", BaseRange: 5.0, FatigueBonus: 0.9},
+	"black-ops":      {Name: "Black Ops", BaseRange: 4.0, FatigueBonus: 0.75},
+}
+
+// jdcRangeBonus is the jump range added per level of Jump Drive Calibration.
+const jdcRangeBonus = 0.2
+
+// Jump fatigue limits.
+const (
+	minJumpFatigue  = 10 * time.Minute
+	maxJumpFatigue  = 5 * time.Hour
+	maxReactivation = 30 * time.Minute
+)
+
+// JumpRange returns a ship's maximum jump distance in light years at the given JDC level.
+func (s jumpShip) JumpRange(jdcLevel int) float64 {
+	return s.BaseRange * (1 + jdcRangeBonus*float64(jdcLevel))
+}
+
+// JumpLeg is one jump-drive jump and the fatigue it leaves behind.
+type JumpLeg struct {
+	FromID       int
+	ToID         int
+	LightYears   float64
+	Fatigue      time.Duration // jump fatigue right after the jump
+	Reactivation time.Duration // wait before the drive can be used again
+}
+
+// applyJumpFatigue returns the fatigue and reactivation timer after jumping
+// effectiveLY light years with the given fatigue already on the clock.
+func applyJumpFatigue(current time.Duration, effectiveLY float64) (time.Duration, time.Duration) {
+	reactivation := time.Duration((1 + effectiveLY) * float64(time.Minute))
+	if wait := current / 10; wait > reactivation {
+		reactivation = wait
+	}
+	if reactivation > maxReactivation {
+		reactivation = maxReactivation
+	}
+
+	base := current
+	if base < minJumpFatigue {
+		base = minJumpFatigue
+	}
+	fatigue := time.Duration(float64(base) * (1 + effectiveLY))
+	if fatigue > maxJumpFatigue {
+		fatigue = maxJumpFatigue
+	}
+	return fatigue, reactivation
+}
+
+// jumpRouteHopCost makes every extra jump outweigh any difference in distance,
+// so routes are ranked by jump count first and total light years second.
+const jumpRouteHopCost = 1000.0
+
+// PlanJumpRoute finds the jump-drive route with the fewest jumps, then the
+// shortest total distance, between two systems. canEnter decides which systems
+// may be jumped into; the start system is always allowed.
+func PlanJumpRoute(positions map[int]Position, startID, endID int, maxRange float64, canEnter func(int) bool) ([]int, error) {
+	if _, ok := positions[startID]; !ok {
+		return nil, errors.New("start system position unknown")
+	}
+	if _, ok := positions[endID]; !ok {
+		return nil, errors.New("destination system position unknown")
+	}
+	if !canEnter(endID) {
+		return nil, errors.New("destination cannot be jumped to")
+	}
+
+	candidates := make([]int, 0, len(positions))
+	for id := range positions {
+		if id != startID && canEnter(id) {
+			candidates = append(candidates, id)
+		}
+	}
+
+	costs := map[int]float64{startID: 0}
+	parents := make(map[int]int)
+	settled := make(map[int]bool)
+	pq := &priorityQueue{{id: startID}}
+	for pq.Len() > 0 {
+		currentID := heap.Pop(pq).(queueItem).id
+		if settled[currentID] {
+			continue
+		}
+		settled[currentID] = true
+		if currentID == endID {
+			return buildPath(parents, startID, endID), nil
+		}
+
+		from := positions[currentID]
+		for _, id := range candidates {
+			if settled[id] {
+				continue
+			}
+			ly := from.LightYearsTo(positions[id])
+			if ly > maxRange {
+				continue
+			}
+			newCost := costs[currentID] + jumpRouteHopCost + ly
+			if known, ok := costs[id]; ok && newCost >= known {
+				continue
+			}
+			costs[id], parents[id] = newCost, currentID
+			heap.Push(pq, queueItem{id: id, priority: newCost})
+		}
+	}
+	return nil, errors.New("no jump route within range")
+}
+
+// JumpLegs works out distance and fatigue for each jump of a route, assuming
+// every jump is taken as soon as the drive reactivates.
+func JumpLegs(positions map[int]Position, path []int, ship jumpShip) []JumpLeg {
+	legs := make([]JumpLeg, 0, len(path))
+	var fatigue time.Duration
+	for n := 1; n < len(path); n++ {
+		ly := positions[path[n-1]].LightYearsTo(positions[path[n]])
+		var reactivation time.Duration
+		fatigue, reactivation = applyJumpFatigue(fatigue, ly*(1-ship.FatigueBonus))
+		legs = append(legs, JumpLeg{
+			FromID:       path[n-1],
+			ToID:         path[n],
+			LightYears:   ly,
+			Fatigue:      fatigue,
+			Reactivation: reactivation,
+		})
+		// Fatigue keeps ticking down while waiting for the drive.
+		fatigue -= reactivation
+	}
+	return legs
+}
@@ -4,8 +4,10 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 )
 
 const (
@@ -31,50 +33,121 @@ func main() {
 		log.Printf("%s Could not load system cache: %v. Names will be fetched live.", logWarn, err)
 	}
 
+	// Name index for autocomplete and suggestions.
+	systemIndex, err := LoadSystemIndex("system_cache.json")
+	if err != nil {
+		log.Printf("%s Could not build system name index: %v", logWarn, err)
+	}
+
+	// Local database for route history.
+	store, err := OpenStore("shortcircuit.db")
+	if err != nil {
+		log.Fatalf("FATAL: Could not open database: %v", err)
+	}
+	defer store.Close()
+
+	// System coordinates for jump-drive routing ship in mapSolarSystems.csv. Any
+	// system missing from it is fetched from ESI in the background once the
+	// services are running, and kept in the database.
+	positions := LoadSystemPositions(store, "mapSolarSystems.csv")
+	locations, err := LoadSystemLocations("mapSolarSystemJumps.csv")
+	if err != nil {
+		log.Printf("%s Could not load system regions: %v", logWarn, err)
+	}
+
+	// EVE SSO, for pushing routes to in-game autopilot. Optional.
+	var ssoClient *SSOClient
+	if clientID := os.Getenv("ESI_CLIENT_ID"); clientID != "" {
+		listenAddr := os.Getenv("SSO_LISTEN_ADDR")
+		if listenAddr == "" {
+			listenAddr = ":8081"
+		}
+		ssoClient = NewSSOClient(clientID, os.Getenv("ESI_CLIENT_SECRET"), os.Getenv("ESI_CALLBACK_URL"), listenAddr, store)
+	} else {
+		log.Printf("%s ESI_CLIENT_ID not set; /login and Set Destination are disabled.", logWarn)
+	}
+
+	// Entries every route avoids unless a server sets its own list with /avoid.
+	defaultAvoid := []string{"Zarzakh"}
+	if v, ok := os.LookupEnv("DEFAULT_AVOID"); ok {
+		defaultAvoid = nil
+		for _, entry := range strings.Split(v, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				defaultAvoid = append(defaultAvoid, entry)
+			}
+		}
+	}
+
 	// --- 2. Build the complete initial graph from all sources ---
 	log.Println("--- Building initial universe graph ---")
-	universeGraph, err := BuildGraphFromCSV("mapSolarSystemJumps.csv")
+	stargateGraph, err := BuildGraphFromCSV("mapSolarSystemJumps.csv")
 	if err != nil {
 		log.Fatalf("FATAL: Could not build stargate graph: %v", err)
 	}
+	graphStore := NewGraphStore(stargateGraph)
 
-	// Add connections from local Tripwire cache
-	tripwireData, err := loadTripwireData("tripwire_data.json")
+	// Add connections from the last saved Tripwire poll, or the fetcher's
+	// cache before the first poll has been saved.
+	tripwireData, err := loadTripwireData("tripwire_latest.json")
 	if err != nil {
-		log.Printf("%s Could not load initial tripwire data: %v", logWarn, err)
+		log.Printf("%s Could not load saved tripwire data: %v", logWarn, err)
+	}
+	if tripwireData == nil {
+		if tripwireData, err = loadTripwireData("tripwire_data.json"); err != nil {
+			log.Printf("%s Could not load initial tripwire data: %v", logWarn, err)
+		}
 	}
 	if tripwireData != nil {
-		AddTripwireWormholesToGraph(universeGraph, tripwireData, esiClient)
+		AddTripwireWormholesToGraph(graphStore, tripwireData, esiClient)
 	}
 
+	// The fetcher writes each Tripwire poll to its cache, tripwire_data.json.
+	// The feed picks it up from there: it is saved, diffed against the previous
+	// poll and handed to the subscribers below.
+	tripwireFeed := NewTripwireFeed("tripwire_data.json", "tripwire_latest.json", tripwireData)
+	tripwireFeed.Subscribe(func(data *TripwireData, _ TripwireDiff) {
+		AddTripwireWormholesToGraph(graphStore, data, esiClient)
+	})
+
+	// Chain changes are posted to each guild's /announce channel, or the
+	// default alert webhook.
+	announcer := NewAnnouncer(esiClient, graphStore, store, os.Getenv("DISCORD_WEB_HOOK"), tripwireData)
+	tripwireFeed.Subscribe(announcer.HandleTripwire)
+
 	// Add live Thera connections from EVE-Scout
 	theraConnections, err := eveScoutClient.GetTheraConnections()
 	if err != nil {
 		log.Printf("%s Could not fetch initial Thera connections: %v", logWarn, err)
 	} else {
-		const theraSystemID = 31000005
-		for _, conn := range theraConnections {
-			if conn.DestinationSystem != nil {
-				destID := conn.DestinationSystem.ID
-				universeGraph[theraSystemID] = append(universeGraph[theraSystemID], destID)
-				universeGraph[destID] = append(universeGraph[destID], theraSystemID)
-			}
-		}
+		graphStore.ReplaceSource(SourceEveScout, hubEdges(theraSystemID, EdgeThera, theraConnections, time.Now()))
 		log.Printf("%s Added %d initial Thera connections.", logSuccess, len(theraConnections))
 	}
 
-	DeduplicateNeighbors(universeGraph)
-	log.Printf("%s Graph built with %d systems.", logSuccess, len(universeGraph))
+	log.Printf("%s Graph built with %d systems.", logSuccess, graphStore.Snapshot().Len())
+
+	// Optional live kills from zKillboard's RedisQ, kept for an hour
+	var killFeed *KillFeed
+	if queueID := os.Getenv("ZKILL_QUEUE_ID"); queueID != "" {
+		redisQURL := os.Getenv("ZKILL_REDISQ_URL")
+		if redisQURL == "" {
+			redisQURL = defaultRedisQURL
+		}
+		killFeed, err = NewKillFeed(redisQURL, queueID, time.Hour)
+		if err != nil {
+			log.Fatalf("FATAL: Could not create kill feed: %v", err)
+		}
+		killFeed.Subscribe(announcer.HandleKills)
+	}
 
-	// --- 3. Create services with the fully-built graph ---
-	var graphMutex sync.RWMutex
-	fetcherService, err := New(cfg.TripwireURL, cfg.TripwireUser, cfg.TripwirePass, universeGraph, &graphMutex)
+	// --- 3. Create services sharing the graph snapshot store ---
+	fetcherService, err := New(cfg.TripwireURL, cfg.TripwireUser, cfg.TripwirePass, graphStore)
 	if err != nil {
 		log.Fatalf("FATAL: Could not create fetcher service: %v", err)
 	}
-	botService := NewService(cfg.BotToken, universeGraph, &graphMutex, esiClient)
-	killUpdater := NewKillDataUpdater(esiClient, "system_kills.json")
-	theraUpdater := NewTheraUpdater(eveScoutClient, universeGraph, &graphMutex)
+	killUpdater := NewKillDataUpdater(esiClient, "system_kills.json", "system_jumps.json", store)
+	killUpdater.Subscribe(announcer.HandleKills)
+	botService := NewService(cfg.BotToken, graphStore, esiClient, tripwireFeed, announcer, killFeed, killUpdater, store, ssoClient, defaultAvoid, systemIndex, positions, locations)
+	theraUpdater := NewTheraUpdater(eveScoutClient, graphStore)
 
 	// --- 4. Start services and handle shutdown ---
 	var servicesWg sync.WaitGroup
@@ -88,14 +161,49 @@ func main() {
 		close(quit)
 	}()
 
-	servicesWg.Add(3)
+	// Wormholes reaching end of life between source refreshes leave the graph here.
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if pruned := graphStore.Prune(); pruned > 0 {
+					log.Printf("%s Pruned %d expired wormhole connections.", logSuccess, pruned)
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	servicesWg.Add(4)
 	go fetcherService.Start(&servicesWg, quit)
+	go tripwireFeed.Start(&servicesWg, quit)
 	go botService.Start(&servicesWg, quit)
 	go theraUpdater.Start(&servicesWg, quit)
+	if ssoClient != nil {
+		servicesWg.Add(1)
+		go ssoClient.Start(&servicesWg, quit)
+	}
+	if killFeed != nil {
+		servicesWg.Add(1)
+		go killFeed.Start(&servicesWg, quit)
+	}
 
 	go killUpdater.Start(&servicesWg, quit)
 	go startHealthCheckServer()
 
+	if systemIDs, err := loadSystemCacheIDs("system_cache.json"); err == nil {
+		var knownSpace []int
+		for _, id := range systemIDs {
+			if id >= 30000000 && id < 31000000 {
+				knownSpace = append(knownSpace, id)
+			}
+		}
+		go positions.Fill(knownSpace)
+	}
+
 	servicesWg.Wait()
 	log.Println("--- All services have shut down. Exiting. ---")
 }
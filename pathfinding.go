@@ -0,0 +1,451 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// EdgeCostFunc returns the cost of taking a jump. A negative cost means the
+// jump must not be taken.
+type EdgeCostFunc func(e Edge) float64
+
+// Heuristic estimates the remaining cost from a system to the search target.
+// It must never overestimate, otherwise A* may return a longer route.
+type Heuristic func(id int) float64
+
+// queueItem is a single entry in the search frontier.
+type queueItem struct {
+	id       int
+	priority float64
+}
+
+// priorityQueue is a min-heap of frontier entries for container/heap.
+type priorityQueue []queueItem
+
+func (pq priorityQueue) Len() int            { return len(pq) }
+func (pq priorityQueue) Less(i, j int) bool  { return pq[i].priority < pq[j].priority }
+func (pq priorityQueue) Swap(i, j int)       { pq[i], pq[j] = pq[j], pq[i] }
+func (pq *priorityQueue) Push(x interface{}) { *pq = append(*pq, x.(queueItem)) }
+func (pq *priorityQueue) Pop() interface{} {
+	old := *pq
+	item := old[len(old)-1]
+	*pq = old[:len(old)-1]
+	return item
+}
+
+// ShortestPath runs Dijkstra's algorithm from startID to endID, or A* when a
+// heuristic is supplied. Returns nil when no route exists.
+func ShortestPath(graph *Graph, startID, endID int, cost EdgeCostFunc, h Heuristic) []int {
+	_, parents, found := search(graph, startID, endID, cost, h)
+	if !found {
+		return nil
+	}
+	return buildPath(parents, startID, endID)
+}
+
+// ShortestPathTree runs Dijkstra's algorithm from startID to every reachable
+// system, returning the cost to reach each one and its parent on the way.
+func ShortestPathTree(graph *Graph, startID int, cost EdgeCostFunc) (map[int]float64, map[int]int) {
+	costs, parents, _ := search(graph, startID, -1, cost, nil)
+	return costs, parents
+}
+
+// search is the shared Dijkstra/A* loop. It stops as soon as endID is settled;
+// pass a negative endID to explore everything reachable. Stale heap entries are
+// skipped on pop rather than updated in place.
+func search(graph *Graph, startID, endID int, cost EdgeCostFunc, h Heuristic) (map[int]float64, map[int]int, bool) {
+	costs := map[int]float64{startID: 0}
+	parents := make(map[int]int)
+	settled := make(map[int]bool)
+
+	pq := &priorityQueue{{id: startID}}
+	for pq.Len() > 0 {
+		currentID := heap.Pop(pq).(queueItem).id
+		if settled[currentID] {
+			continue
+		}
+		settled[currentID] = true
+
+		if currentID == endID {
+			return costs, parents, true
+		}
+
+		for _, e := range graph.Neighbors(currentID) {
+			neighborID := e.ToID
+			if settled[neighborID] {
+				continue
+			}
+			stepCost := cost(e)
+			if stepCost < 0 {
+				continue
+			}
+			newCost := costs[currentID] + stepCost
+			if known, ok := costs[neighborID]; ok && newCost >= known {
+				continue
+			}
+			costs[neighborID], parents[neighborID] = newCost, currentID
+
+			priority := newCost
+			if h != nil {
+				priority += h(neighborID)
+			}
+			heap.Push(pq, queueItem{id: neighborID, priority: priority})
+		}
+	}
+	return costs, parents, false
+}
+
+// KShortestPaths returns up to k loopless routes in increasing cost order using
+// Yen's algorithm. first is the route ShortestPath already found under the same
+// cost and heuristic; it is returned as the first route, not searched again.
+func KShortestPaths(graph *Graph, first []int, k int, cost EdgeCostFunc, h Heuristic) [][]int {
+	if first == nil || k <= 0 {
+		return nil
+	}
+	endID := first[len(first)-1]
+
+	type candidate struct {
+		path []int
+		cost float64
+	}
+	paths := [][]int{first}
+	seen := map[string]bool{fmt.Sprint(first): true}
+	var candidates []candidate
+
+	for len(paths) < k {
+		last := paths[len(paths)-1]
+		for i := 0; i < len(last)-1; i++ {
+			spurID := last[i]
+			root := last[:i+1]
+
+			// Block the next jump of every accepted route sharing this root, and
+			// the root itself so the spur cannot loop back through it.
+			bannedEdges := make(map[[2]int]bool)
+			for _, p := range paths {
+				if len(p) > i+1 && equalPaths(p[:i+1], root) {
+					bannedEdges[[2]int{p[i], p[i+1]}] = true
+				}
+			}
+			bannedNodes := make(map[int]bool, i)
+			for _, id := range root[:i] {
+				bannedNodes[id] = true
+			}
+			spurCost := func(e Edge) float64 {
+				if bannedNodes[e.ToID] || bannedEdges[[2]int{e.FromID, e.ToID}] {
+					return -1
+				}
+				return cost(e)
+			}
+
+			spur := ShortestPath(graph, spurID, endID, spurCost, h)
+			if spur == nil {
+				continue
+			}
+			total := append(append([]int{}, root[:i]...), spur...)
+			key := fmt.Sprint(total)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			candidates = append(candidates, candidate{path: total, cost: pathCost(graph, total, cost)})
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		sort.SliceStable(candidates, func(a, b int) bool {
+			if candidates[a].cost != candidates[b].cost {
+				return candidates[a].cost < candidates[b].cost
+			}
+			return len(candidates[a].path) < len(candidates[b].path)
+		})
+		paths = append(paths, candidates[0].path)
+		candidates = candidates[1:]
+	}
+	return paths
+}
+
+// pathCost sums the cheapest allowed jump between each pair of systems on a path.
+func pathCost(graph *Graph, path []int, cost EdgeCostFunc) float64 {
+	total := 0.0
+	for i := 1; i < len(path); i++ {
+		step := math.Inf(1)
+		for _, e := range graph.Neighbors(path[i-1]) {
+			if e.ToID != path[i] {
+				continue
+			}
+			if c := cost(e); c >= 0 && c < step {
+				step = c
+			}
+		}
+		total += step
+	}
+	return total
+}
+
+func equalPaths(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// RouteOptions controls which jumps FindPreferredPath may take and how they are weighed.
+type RouteOptions struct {
+	Preference   string        // "shortest", "safer", "unsafe" or "least-kills"
+	Avoid        map[int]bool  // systems that must never be entered
+	Ship         string        // ship size class from shipMasses; empty allows every wormhole
+	MinLifetime  time.Duration // skip wormholes with less life left than this
+	AvoidEOL     bool          // prefer any other jump over an end-of-life wormhole
+	Kills        map[int]int   // recent ship+pod kills per system, for "least-kills"
+	KillWeight   float64       // extra cost per kill when preferring least kills
+	Alternatives int           // extra routes to compute besides the best one
+}
+
+// killWeights maps the /route kill_weight option to the cost added per kill.
+var killWeights = map[string]float64{
+	"low":    0.25,
+	"medium": 1,
+	"high":   5,
+}
+
+// avoidPenalty is added to a jump the options would rather not take.
+const avoidPenalty = 100.0
+
+// SkippedJump is a wormhole the route search was not allowed to take, or
+// steered around, and why.
+type SkippedJump struct {
+	Edge   Edge
+	Reason string
+}
+
+// RouteResult is a computed route plus the wormholes left out of it.
+type RouteResult struct {
+	Path         []int
+	Alternatives [][]int // next-best routes, cheapest first
+	Skipped      []SkippedJump
+}
+
+// FindPreferredPath returns the cheapest route between two systems under opts.
+func FindPreferredPath(graph *Graph, startID, endID int, esiClient *ESIClient, opts RouteOptions) []int {
+	return PlanRoute(graph, startID, endID, esiClient, opts).Path
+}
+
+// PlanRoute finds the cheapest route under opts and reports every wormhole the
+// search had to skip on the way.
+func PlanRoute(graph *Graph, startID, endID int, esiClient *ESIClient, opts RouteOptions) RouteResult {
+	now := time.Now()
+	skipped := make(map[[2]int]SkippedJump)
+	penalised := make(map[[2]int]Edge)
+	recording := true // only the main search reports skipped jumps
+
+	cost := func(e Edge) float64 {
+		c, reason := routeEdgeCost(e, esiClient, opts, now)
+		if recording {
+			if reason != "" {
+				skipped[connectionKey(e.FromID, e.ToID)] = SkippedJump{Edge: e, Reason: reason}
+			} else if opts.AvoidEOL && e.IsWormhole() && e.Life == "critical" {
+				penalised[connectionKey(e.FromID, e.ToID)] = e
+			}
+		}
+		return c
+	}
+	var h Heuristic
+	if graph.landmarks != nil {
+		h = graph.landmarks.ForTarget(endID)
+	}
+
+	result := RouteResult{Path: ShortestPath(graph, startID, endID, cost, h)}
+	recording = false
+	if opts.Alternatives > 0 && result.Path != nil {
+		if routes := KShortestPaths(graph, result.Path, opts.Alternatives+1, cost, h); len(routes) > 1 {
+			result.Alternatives = routes[1:]
+		}
+	}
+
+	// End-of-life holes the route managed to steer around count as skipped too.
+	onPath := make(map[[2]int]bool)
+	for i := 1; i < len(result.Path); i++ {
+		onPath[connectionKey(result.Path[i-1], result.Path[i])] = true
+	}
+	for key, e := range penalised {
+		if _, hard := skipped[key]; !hard && !onPath[key] {
+			skipped[key] = SkippedJump{Edge: e, Reason: "end of life"}
+		}
+	}
+
+	result.Skipped = sortedSkipped(skipped)
+	return result
+}
+
+// sortedSkipped flattens skipped jumps keyed by connection into a stable order.
+func sortedSkipped(skipped map[[2]int]SkippedJump) []SkippedJump {
+	list := make([]SkippedJump, 0, len(skipped))
+	for _, sj := range skipped {
+		list = append(list, sj)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i].Edge, list[j].Edge
+		if a.FromID != b.FromID {
+			return a.FromID < b.FromID
+		}
+		return a.ToID < b.ToID
+	})
+	return list
+}
+
+// routeEdgeCost prices a single jump under opts. A restricted jump costs -1 and
+// comes with the reason; avoided or plain unwanted jumps simply cost more.
+func routeEdgeCost(e Edge, esiClient *ESIClient, opts RouteOptions, now time.Time) (float64, string) {
+	toID := e.ToID
+	if opts.Avoid[toID] || e.Expired(now) {
+		return -1, ""
+	}
+	if reason := edgeRestriction(e, opts, now); reason != "" {
+		return -1, reason
+	}
+	c := 1.0
+	preference := opts.Preference
+	if preference == "least-kills" {
+		c += opts.KillWeight * float64(opts.Kills[toID])
+	} else if preference != "shortest" && preference != "" {
+		if sysInfo, err := esiClient.GetSystemDetails(toID); err == nil {
+			isHighSec := sysInfo.SecurityStatus >= 0.5
+			if preference == "safer" && !isHighSec {
+				c += avoidPenalty
+			} else if preference == "unsafe" && isHighSec {
+				c += avoidPenalty
+			}
+		}
+	}
+	if opts.AvoidEOL && e.IsWormhole() && e.Life == "critical" {
+		c += avoidPenalty
+	}
+	return c, ""
+}
+
+// connectionKey identifies a connection regardless of the direction it is travelled.
+func connectionKey(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// buildPath walks the parent chain back from endID. Returns nil if the chain is broken.
+func buildPath(parents map[int]int, startID, endID int) []int {
+	path := []int{}
+	at := endID
+	for {
+		path = append(path, at)
+		if at == startID {
+			break
+		}
+		parent, ok := parents[at]
+		if !ok {
+			return nil
+		}
+		at = parent
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// ---- A* landmark heuristic ----
+
+// LandmarkHeuristic holds precomputed jump distances from a handful of landmark
+// systems. By the triangle inequality |d(L,t) - d(L,v)| is a lower bound on the
+// jumps between v and t, and every jump costs at least 1, so it is admissible.
+// The distances are only valid for the graph they were computed on.
+type LandmarkHeuristic struct {
+	distances []map[int]int
+}
+
+// NewLandmarkHeuristic picks up to count landmarks by farthest-point selection
+// and records the jump distance from each to every reachable system.
+func NewLandmarkHeuristic(graph *Graph, count int) *LandmarkHeuristic {
+	lh := &LandmarkHeuristic{}
+	if graph.Len() == 0 || count <= 0 {
+		return lh
+	}
+
+	// Start from the lowest system ID so the landmark choice is deterministic.
+	next := -1
+	for _, id := range graph.SystemIDs() {
+		if next == -1 || id < next {
+			next = id
+		}
+	}
+
+	for len(lh.distances) < count {
+		dist := jumpDistances(graph, next)
+		lh.distances = append(lh.distances, dist)
+
+		// The next landmark is the system farthest from all chosen landmarks.
+		best, bestDist := -1, -1
+		for id := range dist {
+			nearest := -1
+			for _, d := range lh.distances {
+				if dl, ok := d[id]; ok && (nearest == -1 || dl < nearest) {
+					nearest = dl
+				}
+			}
+			if nearest > bestDist || (nearest == bestDist && id < best) {
+				best, bestDist = id, nearest
+			}
+		}
+		if bestDist <= 0 {
+			break
+		}
+		next = best
+	}
+	return lh
+}
+
+// ForTarget returns a heuristic estimating the jumps from any system to targetID.
+func (lh *LandmarkHeuristic) ForTarget(targetID int) Heuristic {
+	return func(id int) float64 {
+		best := 0
+		for _, dist := range lh.distances {
+			dt, okT := dist[targetID]
+			dv, okV := dist[id]
+			if !okT || !okV {
+				continue
+			}
+			diff := dt - dv
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > best {
+				best = diff
+			}
+		}
+		return float64(best)
+	}
+}
+
+// jumpDistances runs a breadth-first search and returns the jump count to every reachable system.
+func jumpDistances(graph *Graph, fromID int) map[int]int {
+	dist := map[int]int{fromID: 0}
+	queue := []int{fromID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, e := range graph.Neighbors(current) {
+			if _, seen := dist[e.ToID]; !seen {
+				dist[e.ToID] = dist[current] + 1
+				queue = append(queue, e.ToID)
+			}
+		}
+	}
+	return dist
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+)
+
+var (
+	testGraphOnce      sync.Once
+	testGraph          *Graph
+	testLocations      map[int]SystemLocation
+	testGraphLoadError error
+)
+
+// loadTestGraph builds the stargate graph and its landmarks from the shipped
+// CSV once for the whole test binary.
+func loadTestGraph(tb testing.TB) (*Graph, map[int]SystemLocation) {
+	tb.Helper()
+	testGraphOnce.Do(func() {
+		testGraph, testGraphLoadError = BuildGraphFromCSV("mapSolarSystemJumps.csv")
+		if testGraphLoadError != nil {
+			return
+		}
+		testGraph.landmarks = NewLandmarkHeuristic(testGraph, landmarkCount)
+		testLocations, testGraphLoadError = LoadSystemLocations("mapSolarSystemJumps.csv")
+	})
+	if testGraphLoadError != nil {
+		tb.Fatalf("could not load mapSolarSystemJumps.csv: %v", testGraphLoadError)
+	}
+	return testGraph, testLocations
+}
+
+// crossRegionPairs are trade hubs and null-sec staging systems in different regions.
+var crossRegionPairs = [][2]int{
+	{30000142, 30002187}, // Jita -> Amarr
+	{30002659, 30002510}, // Dodixie -> Rens
+	{30000142, 30004759}, // Jita -> 1DQ1-A
+	{30002187, 30002053}, // Amarr -> Hek
+	{30004759, 30002659}, // 1DQ1-A -> Dodixie
+}
+
+// hopCost counts every jump as 1.
+func hopCost(Edge) float64 { return 1 }
+
+// killCost adds a made-up kill count per system, as the least-kills preference does.
+func killCost(kills map[int]int) EdgeCostFunc {
+	return func(e Edge) float64 { return 1 + float64(kills[e.ToID]) }
+}
+
+func TestAStarMatchesDijkstra(t *testing.T) {
+	graph, locations := loadTestGraph(t)
+	ids := graph.SystemIDs()
+	sort.Ints(ids)
+	rng := rand.New(rand.NewSource(1))
+
+	kills := make(map[int]int, len(ids))
+	for _, id := range ids {
+		kills[id] = rng.Intn(4)
+	}
+	costs := map[string]EdgeCostFunc{"hops": hopCost, "kills": killCost(kills)}
+
+	pairs := 0
+	for pairs < 200 {
+		startID, endID := ids[rng.Intn(len(ids))], ids[rng.Intn(len(ids))]
+		if locations[startID].RegionID == locations[endID].RegionID {
+			continue
+		}
+		pairs++
+		h := graph.landmarks.ForTarget(endID)
+		for name, cost := range costs {
+			dijkstra := ShortestPath(graph, startID, endID, cost, nil)
+			astar := ShortestPath(graph, startID, endID, cost, h)
+			if (dijkstra == nil) != (astar == nil) {
+				t.Fatalf("%s %d -> %d: Dijkstra found %v, A* found %v", name, startID, endID, dijkstra, astar)
+			}
+			if dijkstra == nil {
+				continue
+			}
+			if d, a := pathCost(graph, dijkstra, cost), pathCost(graph, astar, cost); d != a {
+				t.Errorf("%s %d -> %d: Dijkstra cost %v, A* cost %v", name, startID, endID, d, a)
+			}
+		}
+	}
+}
+
+func TestKShortestPathsKeepsFirst(t *testing.T) {
+	graph, _ := loadTestGraph(t)
+	startID, endID := crossRegionPairs[0][0], crossRegionPairs[0][1]
+
+	first := ShortestPath(graph, startID, endID, hopCost, nil)
+	routes := KShortestPaths(graph, first, 3, hopCost, nil)
+	if len(routes) != 3 {
+		t.Fatalf("got %d routes, want 3", len(routes))
+	}
+	if !equalPaths(routes[0], first) {
+		t.Errorf("first route %v, want %v", routes[0], first)
+	}
+	for i := 1; i < len(routes); i++ {
+		if pathCost(graph, routes[i], hopCost) < pathCost(graph, routes[i-1], hopCost) {
+			t.Errorf("route %d is cheaper than route %d", i, i-1)
+		}
+	}
+}
+
+func BenchmarkShortestPath(b *testing.B) {
+	graph, _ := loadTestGraph(b)
+	for _, pair := range crossRegionPairs {
+		if ShortestPath(graph, pair[0], pair[1], hopCost, nil) == nil {
+			b.Fatalf("no route %d -> %d", pair[0], pair[1])
+		}
+	}
+
+	b.Run("Dijkstra", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			pair := crossRegionPairs[i%len(crossRegionPairs)]
+			ShortestPath(graph, pair[0], pair[1], hopCost, nil)
+		}
+	})
+	b.Run("AStar", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			pair := crossRegionPairs[i%len(crossRegionPairs)]
+			ShortestPath(graph, pair[0], pair[1], hopCost, graph.landmarks.ForTarget(pair[1]))
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// routeCacheTTL is how long a computed route stays available to the buttons
+// and menus on its embed.
+const routeCacheTTL = 6 * time.Hour
+
+// routeSummary describes one route alternative for the selector menu.
+type routeSummary struct {
+	Jumps         int
+	LowestSec     float64
+	WormholeJumps int
+}
+
+// tripLeg is one stop-to-stop stretch of a multi-waypoint trip.
+type tripLeg struct {
+	From  string
+	To    string
+	Jumps int
+}
+
+// cachedRoute is everything needed to redraw a route embed without
+// recomputing the route or reading it back out of the message.
+type cachedRoute struct {
+	ID            string
+	Title         string // overrides the default embed title when set
+	Note          string // shown as the embed description when set
+	StartName     string
+	EndName       string
+	Ship          string
+	Avoid         map[int]bool
+	Excluded      string   // everything excluded, as entered: guild list plus exclude option
+	AlwaysAvoided []string // the guild's always-avoid list when the route was planned
+	Paths         [][]int  // best route first, then alternatives
+	Summaries     []routeSummary
+	Skipped       []SkippedJump
+	Legs          []tripLeg    // set for /trip and /tour results
+	Changed       map[int]bool // systems new since the route was last run
+	Rerunnable    bool         // saved in route history, so it gets a Re-run button
+	CreatedAt     time.Time
+}
+
+// routeView is what a route embed currently shows: which alternative, which
+// page of the route details and whether high-sec stretches are collapsed.
+type routeView struct {
+	Index   int
+	Page    int
+	Compact bool
+}
+
+// routeCache keeps recently computed routes in memory, keyed by ID.
+type routeCache struct {
+	mu     sync.Mutex
+	routes map[string]*cachedRoute
+}
+
+func newRouteCache() *routeCache {
+	return &routeCache{routes: make(map[string]*cachedRoute)}
+}
+
+// Put stores a route, evicting any that have outlived routeCacheTTL.
+func (c *routeCache) Put(r *cachedRoute) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, old := range c.routes {
+		if time.Since(old.CreatedAt) > routeCacheTTL {
+			delete(c.routes, id)
+		}
+	}
+	c.routes[r.ID] = r
+}
+
+// Get returns a cached route if it exists and has not expired.
+func (c *routeCache) Get(id string) (*cachedRoute, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, ok := c.routes[id]
+	if !ok || time.Since(r.CreatedAt) > routeCacheTTL {
+		return nil, false
+	}
+	return r, true
+}
@@ -7,10 +7,12 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
-// BuildGraphFromCSV reads mapSolarSystemJumps.csv and returns a graph as adjacency list.
-func BuildGraphFromCSV(filename string) (map[int][]int, error) {
+// BuildGraphFromCSV reads mapSolarSystemJumps.csv and returns the stargate graph.
+func BuildGraphFromCSV(filename string) (*Graph, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open CSV file: %w", err)
@@ -23,7 +25,7 @@ func BuildGraphFromCSV(filename string) (map[int][]int, error) {
 		return nil, fmt.Errorf("failed to read CSV data: %w", err)
 	}
 
-	graph := make(map[int][]int)
+	graph := NewGraph()
 
 	// Expected columns: fromRegionID,fromConstellationID,fromSolarSystemID,toSolarSystemID,toConstellationID,toRegionID
 	for i, rec := range records {
@@ -40,36 +42,81 @@ func BuildGraphFromCSV(filename string) (map[int][]int, error) {
 			log.Printf("Invalid system ID at row %d: %v %v", i+1, err1, err2)
 			continue
 		}
-		graph[fromSystem] = append(graph[fromSystem], toSystem)
-		graph[toSystem] = append(graph[toSystem], fromSystem)
+		graph.AddConnection(Edge{FromID: fromSystem, ToID: toSystem, Kind: EdgeStargate, Source: SourceStatic})
 	}
 
 	return graph, nil
 }
 
-// DeduplicateNeighbors ensures no duplicate edges in adjacency lists.
-func DeduplicateNeighbors(graph map[int][]int) {
-	for systemID, neighbors := range graph {
-		unique := make(map[int]bool)
-		deduped := make([]int, 0, len(neighbors))
-		for _, n := range neighbors {
-			if !unique[n] {
-				unique[n] = true
-				deduped = append(deduped, n)
-			}
+// SystemLocation is the constellation and region a system belongs to.
+type SystemLocation struct {
+	ConstellationID int
+	RegionID        int
+}
+
+// LoadSystemLocations reads the region and constellation columns of
+// mapSolarSystemJumps.csv. Systems without stargates (wormhole space) are absent.
+func LoadSystemLocations(filename string) (map[int]SystemLocation, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV data: %w", err)
+	}
+
+	locations := make(map[int]SystemLocation)
+	for i, rec := range records {
+		if i == 0 || len(rec) < 6 {
+			continue
+		}
+		fromRegion, _ := strconv.Atoi(rec[0])
+		fromConstellation, _ := strconv.Atoi(rec[1])
+		fromSystem, _ := strconv.Atoi(rec[2])
+		toSystem, _ := strconv.Atoi(rec[3])
+		toConstellation, _ := strconv.Atoi(rec[4])
+		toRegion, _ := strconv.Atoi(rec[5])
+		if fromSystem != 0 {
+			locations[fromSystem] = SystemLocation{ConstellationID: fromConstellation, RegionID: fromRegion}
+		}
+		if toSystem != 0 {
+			locations[toSystem] = SystemLocation{ConstellationID: toConstellation, RegionID: toRegion}
 		}
-		graph[systemID] = deduped
 	}
+	return locations, nil
 }
 
-// In the file with your graph logic
+// tripwireTimeLayout is the timestamp format used throughout Tripwire's JSON.
+const tripwireTimeLayout = "2006-01-02 15:04:05"
 
-func AddTripwireWormholesToGraph(graph map[int][]int, data *TripwireData, esiClient *ESIClient) {
+// parseTripwireTime parses a Tripwire timestamp, returning the zero time if it is empty or malformed.
+func parseTripwireTime(value string) time.Time {
+	t, err := time.Parse(tripwireTimeLayout, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// AddTripwireWormholesToGraph replaces the store's Tripwire overlay with the
+// fully-mapped wormholes in data and publishes a new snapshot.
+func AddTripwireWormholesToGraph(store *GraphStore, data *TripwireData, esiClient *ESIClient) {
 	if data == nil {
 		return
 	}
 
-	addedCount := 0
+	edges := TripwireEdges(data, esiClient, time.Now())
+	expired := store.ReplaceSource(SourceTripwire, edges)
+	log.Printf("Successfully processed and added %d wormhole connections from Tripwire (%d expired connections dropped).", len(edges), expired)
+}
+
+// TripwireEdges returns an edge for every Tripwire wormhole with both ends mapped
+// that has not yet reached its end of life.
+func TripwireEdges(data *TripwireData, esiClient *ESIClient, seen time.Time) []Edge {
+	var edges []Edge
 	for _, wh := range data.Wormholes {
 		sigA, okA := data.Signatures[wh.InitialID]
 		sigB, okB := data.Signatures[wh.SecondaryID]
@@ -82,11 +129,13 @@ func AddTripwireWormholesToGraph(graph map[int][]int, data *TripwireData, esiCli
 			isSigAValid := sysA_ID != 0 && sigA.SignatureID != nil && *sigA.SignatureID != "???"
 			isSigBValid := sysB_ID != 0 && sigB.SignatureID != nil && *sigB.SignatureID != "???"
 
-			// Use the complete guard. The old if block has been removed.
 			if isSigAValid && isSigBValid {
-				graph[sysA_ID] = append(graph[sysA_ID], sysB_ID)
-				graph[sysB_ID] = append(graph[sysB_ID], sysA_ID)
-				addedCount++
+				e := tripwireEdge(wh, sigA, sigB, sysA_ID, sysB_ID)
+				if e.Expired(seen) {
+					continue
+				}
+				e.LastSeen = seen
+				edges = append(edges, e)
 
 				// Proactively look up the names for these systems and cache them.
 				if esiClient != nil {
@@ -96,33 +145,58 @@ func AddTripwireWormholesToGraph(graph map[int][]int, data *TripwireData, esiCli
 			}
 		}
 	}
-	log.Printf("Successfully processed and added %d wormhole connections from Tripwire.", addedCount)
+	return edges
+}
+
+// tripwireEdge builds the graph edge for a Tripwire wormhole, going from the initial to the secondary signature.
+func tripwireEdge(wh TripwireWormhole, sigA, sigB TripwireSignature, sysA_ID, sysB_ID int) Edge {
+	whType := strings.ToUpper(wh.Type)
+	if whType == "????" {
+		whType = ""
+	}
+
+	// The hole dies when the first of its two signatures does.
+	expiresAt := parseTripwireTime(sigA.LifeLeft)
+	if eolB := parseTripwireTime(sigB.LifeLeft); !eolB.IsZero() && (expiresAt.IsZero() || eolB.Before(expiresAt)) {
+		expiresAt = eolB
+	}
+	updatedAt := parseTripwireTime(sigA.ModifiedTime)
+	if modB := parseTripwireTime(sigB.ModifiedTime); modB.After(updatedAt) {
+		updatedAt = modB
+	}
+
+	return Edge{
+		FromID:        sysA_ID,
+		ToID:          sysB_ID,
+		Kind:          EdgeWormhole,
+		Source:        SourceTripwire,
+		FromSignature: strings.ToUpper(*sigA.SignatureID),
+		ToSignature:   strings.ToUpper(*sigB.SignatureID),
+		WormholeType:  whType,
+		Mass:          wh.Mass,
+		Life:          wh.Life,
+		CreatedAt:     parseTripwireTime(sigA.LifeTime),
+		UpdatedAt:     updatedAt,
+		ExpiresAt:     expiresAt,
+	}
 }
 
 // The parameter needs to change to accept all the new data
-func GraphBuilder(data *TripwireData, esiClient *ESIClient) (map[int][]int, error) {
+func GraphBuilder(data *TripwireData, esiClient *ESIClient) (*Graph, error) {
 	graph, err := BuildGraphFromCSV("mapSolarSystemJumps.csv")
 	if err != nil {
 		// It's better to return an error than to call log.Fatal here
 		return nil, err
 	}
-	DeduplicateNeighbors(graph)
 
-	// Now, call your new and improved function to add wormholes!
+	// Now, add the wormholes on top of the stargates.
 	if data != nil {
-		AddTripwireWormholesToGraph(graph, data, esiClient) // Pass nil for esiClient for now
-	}
-
-	// This debug printing is great for checking your work
-	fmt.Printf("Graph contains %d systems.\n", len(graph))
-	exampleSystemID := 30000142 // Jita
-	if neighbors, ok := graph[exampleSystemID]; ok {
-		fmt.Printf("System %d has %d direct jumps:\n", exampleSystemID, len(neighbors))
-		for _, n := range neighbors {
-			fmt.Printf("  -> %d\n", n)
+		for _, e := range TripwireEdges(data, esiClient, time.Now()) {
+			graph.AddConnection(e)
 		}
 	}
 
+	log.Printf("%s Graph contains %d systems.", logSuccess, graph.Len())
 	return graph, nil
 }
 
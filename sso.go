@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EVE SSO (OAuth2) endpoints.
+const (
+	ssoAuthorizeURL = "https://login.eveonline.com/v2/oauth/authorize"
+	ssoTokenURL     = "https://login.eveonline.com/v2/oauth/token"
+)
+
+// ssoScopes are the ESI scopes requested when a character is linked.
+var ssoScopes = []string{"esi-ui.write_waypoint.v1", "esi-location.read_location.v1"}
+
+// ssoLoginTTL is how long a login link stays valid.
+const ssoLoginTTL = 10 * time.Minute
+
+// ErrNotLinked is returned when a Discord user has no linked character.
+var ErrNotLinked = errors.New("no linked character")
+
+// ErrMissingScope is returned when ESI refuses a call because the character's
+// token lacks the scope, typically because it was linked before the scope was added.
+var ErrMissingScope = errors.New("token is missing a required scope")
+
+// LinkedCharacter is an EVE character a Discord user has authorised through SSO.
+type LinkedCharacter struct {
+	CharacterID   int       `json:"character_id"`
+	CharacterName string    `json:"character_name"`
+	AccessToken   string    `json:"access_token"`
+	RefreshToken  string    `json:"refresh_token"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// pendingLogin ties an SSO state value back to the Discord user who asked for it.
+type pendingLogin struct {
+	userID    string
+	createdAt time.Time
+}
+
+// SSOClient links Discord users to EVE characters and keeps their tokens fresh.
+// It serves the SSO callback on its own small HTTP server.
+type SSOClient struct {
+	clientID     string
+	clientSecret string
+	callbackURL  string
+	listenAddr   string
+	store        *Store
+	httpClient   *http.Client
+
+	mu      sync.Mutex
+	pending map[string]pendingLogin
+}
+
+func NewSSOClient(clientID, clientSecret, callbackURL, listenAddr string, store *Store) *SSOClient {
+	return &SSOClient{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		callbackURL:  callbackURL,
+		listenAddr:   listenAddr,
+		store:        store,
+		httpClient:   &http.Client{Timeout: 15 * time.Second},
+		pending:      make(map[string]pendingLogin),
+	}
+}
+
+// Start runs the callback server until quit is closed.
+func (c *SSOClient) Start(wg *sync.WaitGroup, quit chan struct{}) {
+	defer wg.Done()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", c.handleCallback)
+	server := &http.Server{Addr: c.listenAddr, Handler: mux}
+
+	go func() {
+		log.Printf("[SSO] Callback server listening on %s", c.listenAddr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("[SSO] ERROR: callback server stopped: %v", err)
+		}
+	}()
+
+	<-quit
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(ctx)
+	log.Println("[SSO] Callback server stopped.")
+}
+
+// LoginURL returns an SSO link that, once completed, links a character to the Discord user.
+func (c *SSOClient) LoginURL(userID string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	state := hex.EncodeToString(buf)
+
+	c.mu.Lock()
+	for key, p := range c.pending {
+		if time.Since(p.createdAt) > ssoLoginTTL {
+			delete(c.pending, key)
+		}
+	}
+	c.pending[state] = pendingLogin{userID: userID, createdAt: time.Now()}
+	c.mu.Unlock()
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("redirect_uri", c.callbackURL)
+	q.Set("client_id", c.clientID)
+	q.Set("scope", strings.Join(ssoScopes, " "))
+	q.Set("state", state)
+	return ssoAuthorizeURL + "?" + q.Encode(), nil
+}
+
+// handleCallback finishes a login: it swaps the code for tokens and saves the character.
+func (c *SSOClient) handleCallback(w http.ResponseWriter, r *http.Request) {
+	state, code := r.URL.Query().Get("state"), r.URL.Query().Get("code")
+
+	c.mu.Lock()
+	login, ok := c.pending[state]
+	delete(c.pending, state)
+	c.mu.Unlock()
+	if !ok || code == "" || time.Since(login.createdAt) > ssoLoginTTL {
+		http.Error(w, "This login link has expired. Please run /login again in Discord.", http.StatusBadRequest)
+		return
+	}
+
+	char, err := c.requestToken(url.Values{"grant_type": {"authorization_code"}, "code": {code}})
+	if err != nil {
+		log.Printf("[SSO] ERROR: token exchange failed: %v", err)
+		http.Error(w, "Login failed. Please try again.", http.StatusBadGateway)
+		return
+	}
+	if err := c.store.SaveCharacter(login.userID, char); err != nil {
+		log.Printf("[SSO] ERROR: could not save character: %v", err)
+		http.Error(w, "Login failed. Please try again.", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[SSO] %s Linked %s to Discord user %s.", logSuccess, char.CharacterName, login.userID)
+	fmt.Fprintf(w, "Linked %s. You can close this window and return to Discord.", char.CharacterName)
+}
+
+// Character returns the user's linked character with a valid access token,
+// refreshing and saving the token first if it is about to expire.
+func (c *SSOClient) Character(userID string) (*LinkedCharacter, error) {
+	char, err := c.store.GetCharacter(userID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrNotLinked
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Until(char.ExpiresAt) > time.Minute {
+		return char, nil
+	}
+
+	refreshed, err := c.requestToken(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {char.RefreshToken}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token for %s: %w", char.CharacterName, err)
+	}
+	if err := c.store.SaveCharacter(userID, refreshed); err != nil {
+		return nil, err
+	}
+	return refreshed, nil
+}
+
+// requestToken calls the SSO token endpoint and reads the character from the returned JWT.
+func (c *SSOClient) requestToken(form url.Values) (*LinkedCharacter, error) {
+	req, err := http.NewRequest("POST", ssoTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(c.clientID, c.clientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", esiUserAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sso returned non-200 status: %d", resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken  string `json:"access_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	charID, name, err := characterFromJWT(token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	return &LinkedCharacter{
+		CharacterID:   charID,
+		CharacterName: name,
+		AccessToken:   token.AccessToken,
+		RefreshToken:  token.RefreshToken,
+		ExpiresAt:     time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// characterFromJWT reads the character ID and name from an SSO access token.
+// The token comes straight from the SSO over TLS, so the signature is not checked here.
+func characterFromJWT(accessToken string) (int, string, error) {
+	parts := strings.Split(accessToken, ".")
+	if len(parts) != 3 {
+		return 0, "", errors.New("malformed access token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to decode access token: %w", err)
+	}
+	var claims struct {
+		Sub  string `json:"sub"` // "CHARACTER:EVE:<id>"
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return 0, "", fmt.Errorf("failed to parse access token: %w", err)
+	}
+	charID, err := strconv.Atoi(strings.TrimPrefix(claims.Sub, "CHARACTER:EVE:"))
+	if err != nil {
+		return 0, "", fmt.Errorf("unexpected token subject %q", claims.Sub)
+	}
+	return charID, claims.Name, nil
+}
+
+// SetWaypoints replaces the character's autopilot route with the given systems, in order.
+func (c *SSOClient) SetWaypoints(char *LinkedCharacter, systemIDs []int) error {
+	for n, id := range systemIDs {
+		q := url.Values{}
+		q.Set("destination_id", strconv.Itoa(id))
+		q.Set("add_to_beginning", "false")
+		q.Set("clear_other_waypoints", strconv.FormatBool(n == 0))
+		if err := esiAuthPost("/ui/autopilot/waypoint/?"+q.Encode(), char.AccessToken); err != nil {
+			return fmt.Errorf("failed to set waypoint %d of %d: %w", n+1, len(systemIDs), err)
+		}
+	}
+	return nil
+}
+
+// CharacterLocation returns the solar system the character is currently in.
+func (c *SSOClient) CharacterLocation(char *LinkedCharacter) (int, error) {
+	var location struct {
+		SolarSystemID int `json:"solar_system_id"`
+	}
+	err := esiAuthGetJSON(fmt.Sprintf("/characters/%d/location/", char.CharacterID), char.AccessToken, &location)
+	if err != nil {
+		return 0, err
+	}
+	return location.SolarSystemID, nil
+}
@@ -0,0 +1,510 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Store is the bot's local database, a single BoltDB file.
+type Store struct {
+	db *bolt.DB
+}
+
+// Bucket names.
+var (
+	bucketRoutes       = []byte("routes")           // route ID -> RouteRecord JSON
+	bucketRouteHistory = []byte("route_history")    // guild/user -> time+ID -> route ID
+	bucketCharacters   = []byte("characters")       // Discord user ID -> LinkedCharacter JSON
+	bucketGuildAvoid   = []byte("guild_avoid")      // guild ID -> JSON list of always-avoided entries
+	bucketAnnounce     = []byte("guild_announce")   // guild ID -> AnnounceConfig JSON
+	bucketHostile      = []byte("guild_hostile")    // guild ID -> HostileAlertConfig JSON
+	bucketJumpHistory  = []byte("jump_history")     // system ID -> JSON list of HourlyReading of ship jumps, oldest first
+	bucketKillHistory  = []byte("kill_history")     // system ID -> JSON list of HourlyReading of ship kills, oldest first
+	bucketPositions    = []byte("system_positions") // system ID -> Position JSON
+	bucketWebhooks     = []byte("channel_webhooks") // channel ID -> ChannelWebhook JSON
+)
+
+// ErrNotFound is returned when a record does not exist in the store.
+var ErrNotFound = errors.New("not found")
+
+// RouteRecord is one /route request as it was asked and answered.
+type RouteRecord struct {
+	ID        string            `json:"id"`
+	GuildID   string            `json:"guild_id"`
+	UserID    string            `json:"user_id"`
+	Options   map[string]string `json:"options"` // the command options as given
+	StartID   int               `json:"start_id"`
+	EndID     int               `json:"end_id"`
+	Path      []int             `json:"path"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// HourlyReading is one of ESI's hourly counts for a system, of ship jumps or
+// ship kills depending on the history it is kept in.
+type HourlyReading struct {
+	At    time.Time `json:"at"`
+	Count int       `json:"count"`
+}
+
+// ChannelWebhook is the webhook the bot created in a channel for its alerts.
+// Every alert feature posting to the channel shares it.
+type ChannelWebhook struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// OpenStore opens (or creates) the database file and makes sure every bucket exists.
+func OpenStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database '%s': %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketRoutes, bucketRouteHistory, bucketCharacters, bucketGuildAvoid, bucketAnnounce, bucketHostile, bucketJumpHistory, bucketKillHistory, bucketPositions, bucketWebhooks} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (st *Store) Close() error {
+	return st.db.Close()
+}
+
+// historyScope is the nested history bucket for one user in one guild (or DMs).
+func historyScope(guildID, userID string) []byte {
+	return []byte(guildID + "/" + userID)
+}
+
+// historyKey sorts history entries by time, with the ID keeping keys unique.
+func historyKey(t time.Time, id string) []byte {
+	key := make([]byte, 8, 8+len(id))
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return append(key, id...)
+}
+
+// SaveRoute records a route and adds it to its user's history. Only the
+// user's keep most recent routes are kept; older ones are deleted.
+func (st *Store) SaveRoute(rec *RouteRecord, keep int) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode route: %w", err)
+	}
+	return st.db.Update(func(tx *bolt.Tx) error {
+		routes := tx.Bucket(bucketRoutes)
+		if err := routes.Put([]byte(rec.ID), data); err != nil {
+			return err
+		}
+		scope, err := tx.Bucket(bucketRouteHistory).CreateBucketIfNotExists(historyScope(rec.GuildID, rec.UserID))
+		if err != nil {
+			return err
+		}
+		if err := scope.Put(historyKey(rec.CreatedAt, rec.ID), []byte(rec.ID)); err != nil {
+			return err
+		}
+
+		// Collect first: bbolt cursors must not be used across deletes.
+		var stale [][]byte
+		c := scope.Cursor()
+		n := 0
+		for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+			if n++; n > keep {
+				stale = append(stale, k)
+			}
+		}
+		for _, k := range stale {
+			if err := routes.Delete(scope.Get(k)); err != nil {
+				return err
+			}
+			if err := scope.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetRoute loads a route by ID.
+func (st *Store) GetRoute(id string) (*RouteRecord, error) {
+	var rec RouteRecord
+	err := st.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketRoutes).Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, &rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// RouteHistory returns a user's most recent routes in a guild, newest first.
+func (st *Store) RouteHistory(guildID, userID string, limit int) ([]RouteRecord, error) {
+	var records []RouteRecord
+	err := st.db.View(func(tx *bolt.Tx) error {
+		scope := tx.Bucket(bucketRouteHistory).Bucket(historyScope(guildID, userID))
+		if scope == nil {
+			return nil
+		}
+		routes := tx.Bucket(bucketRoutes)
+		c := scope.Cursor()
+		for k, id := c.Last(); k != nil && len(records) < limit; k, id = c.Prev() {
+			data := routes.Get(id)
+			if data == nil {
+				continue
+			}
+			var rec RouteRecord
+			if err := json.Unmarshal(data, &rec); err != nil {
+				return err
+			}
+			records = append(records, rec)
+		}
+		return nil
+	})
+	return records, err
+}
+
+// SaveCharacter links an EVE character (and its SSO tokens) to a Discord user.
+func (st *Store) SaveCharacter(userID string, char *LinkedCharacter) error {
+	data, err := json.Marshal(char)
+	if err != nil {
+		return fmt.Errorf("failed to encode character: %w", err)
+	}
+	return st.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketCharacters).Put([]byte(userID), data)
+	})
+}
+
+// GetCharacter returns the character linked to a Discord user.
+func (st *Store) GetCharacter(userID string) (*LinkedCharacter, error) {
+	var char LinkedCharacter
+	err := st.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketCharacters).Get([]byte(userID))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, &char)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &char, nil
+}
+
+// GuildAvoidList returns a guild's always-avoid entries. ok is false if the
+// guild has never changed its list.
+func (st *Store) GuildAvoidList(guildID string) (entries []string, ok bool, err error) {
+	err = st.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketGuildAvoid).Get([]byte(guildID))
+		if data == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(data, &entries)
+	})
+	return entries, ok, err
+}
+
+// SetGuildAvoidList replaces a guild's always-avoid entries.
+func (st *Store) SetGuildAvoidList(guildID string, entries []string) error {
+	if entries == nil {
+		entries = []string{} // an empty list is still a choice, unlike no list
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode avoid list: %w", err)
+	}
+	return st.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketGuildAvoid).Put([]byte(guildID), data)
+	})
+}
+
+// SaveAnnounceConfig stores a guild's chain announcement settings.
+func (st *Store) SaveAnnounceConfig(guildID string, cfg AnnounceConfig) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to encode announcement settings: %w", err)
+	}
+	return st.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketAnnounce).Put([]byte(guildID), data)
+	})
+}
+
+// GetAnnounceConfig returns a guild's chain announcement settings.
+func (st *Store) GetAnnounceConfig(guildID string) (*AnnounceConfig, error) {
+	var cfg AnnounceConfig
+	err := st.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketAnnounce).Get([]byte(guildID))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, &cfg)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// DeleteAnnounceConfig turns chain announcements off for a guild.
+func (st *Store) DeleteAnnounceConfig(guildID string) error {
+	return st.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketAnnounce).Delete([]byte(guildID))
+	})
+}
+
+// AnnounceConfigs returns the announcement settings of every guild, keyed by guild ID.
+func (st *Store) AnnounceConfigs() (map[string]AnnounceConfig, error) {
+	configs := make(map[string]AnnounceConfig)
+	err := st.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketAnnounce).ForEach(func(k, v []byte) error {
+			var cfg AnnounceConfig
+			if err := json.Unmarshal(v, &cfg); err != nil {
+				return err
+			}
+			configs[string(k)] = cfg
+			return nil
+		})
+	})
+	return configs, err
+}
+
+// SaveHostileConfig stores a guild's hostile activity alert settings.
+func (st *Store) SaveHostileConfig(guildID string, cfg HostileAlertConfig) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to encode hostile alert settings: %w", err)
+	}
+	return st.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketHostile).Put([]byte(guildID), data)
+	})
+}
+
+// GetHostileConfig returns a guild's hostile activity alert settings.
+func (st *Store) GetHostileConfig(guildID string) (*HostileAlertConfig, error) {
+	var cfg HostileAlertConfig
+	err := st.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketHostile).Get([]byte(guildID))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, &cfg)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// DeleteHostileConfig turns hostile activity alerts off for a guild.
+func (st *Store) DeleteHostileConfig(guildID string) error {
+	return st.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketHostile).Delete([]byte(guildID))
+	})
+}
+
+// HostileConfigs returns the hostile alert settings of every guild, keyed by guild ID.
+func (st *Store) HostileConfigs() (map[string]HostileAlertConfig, error) {
+	configs := make(map[string]HostileAlertConfig)
+	err := st.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketHostile).ForEach(func(k, v []byte) error {
+			var cfg HostileAlertConfig
+			if err := json.Unmarshal(v, &cfg); err != nil {
+				return err
+			}
+			configs[string(k)] = cfg
+			return nil
+		})
+	})
+	return configs, err
+}
+
+// SaveChannelWebhook records the webhook the bot uses in a channel.
+func (st *Store) SaveChannelWebhook(channelID string, hook ChannelWebhook) error {
+	data, err := json.Marshal(hook)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook: %w", err)
+	}
+	return st.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketWebhooks).Put([]byte(channelID), data)
+	})
+}
+
+// GetChannelWebhook returns the webhook the bot uses in a channel.
+func (st *Store) GetChannelWebhook(channelID string) (*ChannelWebhook, error) {
+	var hook ChannelWebhook
+	err := st.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketWebhooks).Get([]byte(channelID))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, &hook)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// DeleteChannelWebhook forgets the webhook the bot used in a channel.
+func (st *Store) DeleteChannelWebhook(channelID string) error {
+	return st.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketWebhooks).Delete([]byte(channelID))
+	})
+}
+
+// AddJumpReadings appends one hourly reading to each system's jump history and
+// drops readings older than retention.
+func (st *Store) AddJumpReadings(at time.Time, jumps map[int]int, retention time.Duration) error {
+	return st.addReadings(bucketJumpHistory, at, jumps, retention)
+}
+
+// AddKillReadings appends one hourly reading to each system's kill history and
+// drops readings older than retention.
+func (st *Store) AddKillReadings(at time.Time, kills map[int]int, retention time.Duration) error {
+	return st.addReadings(bucketKillHistory, at, kills, retention)
+}
+
+// addReadings appends one hourly reading per system to a history bucket.
+// Systems missing from counts had none that hour; they get a zero reading only
+// if they already have a history, and a history with nothing but zeros left is
+// removed.
+func (st *Store) addReadings(bucket []byte, at time.Time, counts map[int]int, retention time.Duration) error {
+	cutoff := at.Add(-retention)
+	return st.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		histories := make(map[int][]HourlyReading)
+		err := b.ForEach(func(k, v []byte) error {
+			id, err := strconv.Atoi(string(k))
+			if err != nil {
+				return nil
+			}
+			var readings []HourlyReading
+			if err := json.Unmarshal(v, &readings); err != nil {
+				return err
+			}
+			histories[id] = readings
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for id := range counts {
+			if _, ok := histories[id]; !ok {
+				histories[id] = nil
+			}
+		}
+
+		for id, readings := range histories {
+			var kept []HourlyReading
+			busy := false
+			for _, r := range append(readings, HourlyReading{At: at, Count: counts[id]}) {
+				if r.At.Before(cutoff) {
+					continue
+				}
+				kept = append(kept, r)
+				busy = busy || r.Count > 0
+			}
+			key := []byte(strconv.Itoa(id))
+			if !busy {
+				if err := b.Delete(key); err != nil {
+					return err
+				}
+				continue
+			}
+			data, err := json.Marshal(kept)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s of system %d: %w", bucket, id, err)
+			}
+			if err := b.Put(key, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// JumpHistory returns the jump readings kept for the given systems, oldest
+// first. Systems without a history are left out.
+func (st *Store) JumpHistory(systemIDs []int) (map[int][]HourlyReading, error) {
+	return st.readingHistory(bucketJumpHistory, systemIDs)
+}
+
+// KillHistory returns the kill readings kept for the given systems, oldest
+// first. Systems without a history are left out.
+func (st *Store) KillHistory(systemIDs []int) (map[int][]HourlyReading, error) {
+	return st.readingHistory(bucketKillHistory, systemIDs)
+}
+
+// readingHistory returns the readings a history bucket holds for the given systems.
+func (st *Store) readingHistory(bucket []byte, systemIDs []int) (map[int][]HourlyReading, error) {
+	histories := make(map[int][]HourlyReading)
+	err := st.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		for _, id := range systemIDs {
+			data := b.Get([]byte(strconv.Itoa(id)))
+			if data == nil {
+				continue
+			}
+			var readings []HourlyReading
+			if err := json.Unmarshal(data, &readings); err != nil {
+				return err
+			}
+			histories[id] = readings
+		}
+		return nil
+	})
+	return histories, err
+}
+
+// SystemPositions returns every system position saved so far, keyed by system ID.
+func (st *Store) SystemPositions() (map[int]Position, error) {
+	positions := make(map[int]Position)
+	err := st.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketPositions).ForEach(func(k, v []byte) error {
+			id, err := strconv.Atoi(string(k))
+			if err != nil {
+				return nil
+			}
+			var pos Position
+			if err := json.Unmarshal(v, &pos); err != nil {
+				return err
+			}
+			positions[id] = pos
+			return nil
+		})
+	})
+	return positions, err
+}
+
+// SaveSystemPositions adds or replaces the positions of the given systems.
+func (st *Store) SaveSystemPositions(positions map[int]Position) error {
+	return st.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketPositions)
+		for id, pos := range positions {
+			data, err := json.Marshal(pos)
+			if err != nil {
+				return fmt.Errorf("failed to encode position of system %d: %w", id, err)
+			}
+			if err := b.Put([]byte(strconv.Itoa(id)), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
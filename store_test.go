@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveRouteTrimsHistory(t *testing.T) {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	defer store.Close()
+
+	start := time.Now()
+	for n := 0; n < 5; n++ {
+		rec := &RouteRecord{ID: fmt.Sprint(n), GuildID: "g", UserID: "u", CreatedAt: start.Add(time.Duration(n) * time.Minute)}
+		if err := store.SaveRoute(rec, 3); err != nil {
+			t.Fatalf("SaveRoute %d: %v", n, err)
+		}
+	}
+	// Another user's routes are trimmed separately.
+	if err := store.SaveRoute(&RouteRecord{ID: "other", GuildID: "g", UserID: "v", CreatedAt: start}, 3); err != nil {
+		t.Fatalf("SaveRoute other: %v", err)
+	}
+
+	records, err := store.RouteHistory("g", "u", 10)
+	if err != nil {
+		t.Fatalf("RouteHistory: %v", err)
+	}
+	var ids []string
+	for _, rec := range records {
+		ids = append(ids, rec.ID)
+	}
+	if fmt.Sprint(ids) != "[4 3 2]" {
+		t.Errorf("history %v, want [4 3 2]", ids)
+	}
+	for _, id := range []string{"0", "1"} {
+		if _, err := store.GetRoute(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("route %s: got %v, want ErrNotFound", id, err)
+		}
+	}
+	if _, err := store.GetRoute("other"); err != nil {
+		t.Errorf("other user's route: %v", err)
+	}
+}
+
+func TestKillReadingsRetention(t *testing.T) {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	defer store.Close()
+
+	start := time.Now()
+	for hour := 0; hour < 4; hour++ {
+		kills := map[int]int{1: hour + 1}
+		if hour == 0 {
+			kills[2] = 5 // then quiet, so its history is dropped once the reading expires
+		}
+		if err := store.AddKillReadings(start.Add(time.Duration(hour)*time.Hour), kills, 2*time.Hour); err != nil {
+			t.Fatalf("AddKillReadings: %v", err)
+		}
+	}
+
+	history, err := store.KillHistory([]int{1, 2})
+	if err != nil {
+		t.Fatalf("KillHistory: %v", err)
+	}
+	var counts []int
+	for _, r := range history[1] {
+		counts = append(counts, r.Count)
+	}
+	if fmt.Sprint(counts) != "[2 3 4]" {
+		t.Errorf("system 1 readings %v, want [2 3 4]", counts)
+	}
+	if _, ok := history[2]; ok {
+		t.Errorf("system 2 kept %v, want no history", history[2])
+	}
+	if jumps, err := store.JumpHistory([]int{1}); err != nil || len(jumps) != 0 {
+		t.Errorf("jump history %v (%v), want empty", jumps, err)
+	}
+}
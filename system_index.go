@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// systemAliases are extra search terms for well-known systems, mapped to the system name.
+var systemAliases = map[string]string{
+	"eve-scout":  "Thera",
+	"evescout":   "Thera",
+	"thera hub":  "Thera",
+	"turnur hub": "Turnur",
+}
+
+// systemIndexEntry is one searchable system.
+type systemIndexEntry struct {
+	ID       int
+	Name     string
+	Security float64
+	lower    string
+}
+
+// SystemIndex answers name lookups and ranked suggestions over every known system.
+type SystemIndex struct {
+	entries []systemIndexEntry
+	byName  map[string]int // lower-case name -> index into entries
+}
+
+// systemMatch is a ranked search result. Lower ranks are better.
+type systemMatch struct {
+	entry systemIndexEntry
+	rank  int
+}
+
+// Match ranks, best first.
+const (
+	rankExact = iota
+	rankAlias
+	rankPrefix
+	rankWordPrefix
+	rankSubstring
+	rankSubsequence
+)
+
+// LoadSystemIndex builds the index from system_cache.json.
+func LoadSystemIndex(filePath string) (*SystemIndex, error) {
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		return &SystemIndex{}, err
+	}
+	var raw map[string]struct {
+		Name           string  `json:"name"`
+		SecurityStatus float64 `json:"security_status"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return &SystemIndex{}, err
+	}
+
+	idx := &SystemIndex{byName: make(map[string]int, len(raw))}
+	for key, sys := range raw {
+		id, err := strconv.Atoi(key)
+		if err != nil || sys.Name == "" {
+			continue
+		}
+		idx.entries = append(idx.entries, systemIndexEntry{
+			ID:       id,
+			Name:     sys.Name,
+			Security: sys.SecurityStatus,
+			lower:    strings.ToLower(sys.Name),
+		})
+	}
+	sort.Slice(idx.entries, func(a, b int) bool { return idx.entries[a].Name < idx.entries[b].Name })
+	for n, e := range idx.entries {
+		idx.byName[e.lower] = n
+	}
+	return idx, nil
+}
+
+// Len returns the number of indexed systems.
+func (idx *SystemIndex) Len() int {
+	return len(idx.entries)
+}
+
+// Lookup finds a system by exact name or alias, ignoring case.
+func (idx *SystemIndex) Lookup(name string) (systemIndexEntry, bool) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := systemAliases[key]; ok {
+		key = strings.ToLower(alias)
+	}
+	n, ok := idx.byName[key]
+	if !ok {
+		return systemIndexEntry{}, false
+	}
+	return idx.entries[n], true
+}
+
+// Suggest returns up to limit systems matching query, best first. Matches are
+// ranked exact, alias, prefix, word prefix, substring and finally letters in
+// order; J-codes also match without the leading J.
+func (idx *SystemIndex) Suggest(query string, limit int) []systemIndexEntry {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" {
+		return nil
+	}
+
+	var matches []systemMatch
+	if alias, ok := systemAliases[q]; ok {
+		if e, ok := idx.Lookup(alias); ok {
+			matches = append(matches, systemMatch{entry: e, rank: rankAlias})
+		}
+	}
+	for alias, target := range systemAliases {
+		if alias != q && strings.HasPrefix(alias, q) {
+			if e, ok := idx.Lookup(target); ok {
+				matches = append(matches, systemMatch{entry: e, rank: rankAlias})
+			}
+		}
+	}
+
+	// "j1234" and "1234" both look for J-codes
+	jcode := strings.TrimPrefix(q, "j")
+	_, numeric := strconv.Atoi(jcode)
+	isJCode := numeric == nil
+
+	for _, e := range idx.entries {
+		rank := -1
+		switch {
+		case e.lower == q:
+			rank = rankExact
+		case strings.HasPrefix(e.lower, q):
+			rank = rankPrefix
+		case isJCode && strings.HasPrefix(e.lower, "j"+jcode):
+			rank = rankPrefix
+		case strings.Contains(e.lower, " "+q) || strings.Contains(e.lower, "-"+q):
+			rank = rankWordPrefix
+		case strings.Contains(e.lower, q):
+			rank = rankSubstring
+		case len(q) >= 3 && isSubsequence(q, e.lower):
+			rank = rankSubsequence
+		}
+		if rank >= 0 {
+			matches = append(matches, systemMatch{entry: e, rank: rank})
+		}
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].rank != matches[b].rank {
+			return matches[a].rank < matches[b].rank
+		}
+		return len(matches[a].entry.Name) < len(matches[b].entry.Name)
+	})
+
+	seen := make(map[int]bool)
+	var results []systemIndexEntry
+	for _, m := range matches {
+		if len(results) == limit {
+			break
+		}
+		if !seen[m.entry.ID] {
+			seen[m.entry.ID] = true
+			results = append(results, m.entry)
+		}
+	}
+	return results
+}
+
+// isSubsequence reports whether every character of q appears in s, in order.
+func isSubsequence(q, s string) bool {
+	qr := []rune(q)
+	i := 0
+	for _, r := range s {
+		if i < len(qr) && qr[i] == r {
+			i++
+		}
+	}
+	return i == len(qr)
+}
+
+// Closest returns up to limit systems whose names are near misses for name,
+// for "did you mean" suggestions. Candidates need a small edit distance or
+// enough three-letter chunks in common; the closest come first.
+func (idx *SystemIndex) Closest(name string, limit int) []systemIndexEntry {
+	q := strings.ToLower(strings.TrimSpace(name))
+	if q == "" {
+		return nil
+	}
+	maxDist := len(q) / 3
+	if maxDist < 2 {
+		maxDist = 2
+	}
+	qGrams := trigrams(q)
+
+	type scored struct {
+		entry systemIndexEntry
+		score float64
+	}
+	var candidates []scored
+	for _, e := range idx.entries {
+		sim := trigramSimilarity(qGrams, trigrams(e.lower))
+		dist := editDistance(q, e.lower)
+		if dist > maxDist && sim < 0.4 {
+			continue
+		}
+		candidates = append(candidates, scored{entry: e, score: float64(dist) - sim})
+	}
+	sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].score < candidates[b].score })
+
+	var results []systemIndexEntry
+	for _, c := range candidates {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, c.entry)
+	}
+	return results
+}
+
+// editDistance returns the Levenshtein distance between two strings, counting
+// a swap of two adjacent letters as a single edit.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	// rows i-2, i-1 and i of the distance table
+	prev2 := make([]int, len(br)+1)
+	prev := make([]int, len(br)+1)
+	curr := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ar[i-1] == br[j-2] && ar[i-2] == br[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(br)]
+}
+
+// trigrams returns the set of three-letter chunks in s, padded so short names still have some.
+func trigrams(s string) map[string]bool {
+	r := []rune("  " + s + " ")
+	grams := make(map[string]bool, len(r))
+	for i := 0; i+3 <= len(r); i++ {
+		grams[string(r[i:i+3])] = true
+	}
+	return grams
+}
+
+// trigramSimilarity is the share of trigrams two sets have in common (Jaccard index).
+func trigramSimilarity(a, b map[string]bool) float64 {
+	shared := 0
+	for g := range a {
+		if b[g] {
+			shared++
+		}
+	}
+	total := len(a) + len(b) - shared
+	if total == 0 {
+		return 0
+	}
+	return float64(shared) / float64(total)
+}
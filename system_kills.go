@@ -2,24 +2,122 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"sync"
 	"time"
 )
 
+// trendWindow is how much hourly kill and jump history is kept to compare the
+// latest counts against.
+const trendWindow = 24 * time.Hour
+
+// EsiSystemJumps is one entry from ESI's /universe/system_jumps/: the ship jumps
+// into a system over the last hour. Wormhole systems are never listed.
+type EsiSystemJumps struct {
+	SystemID  int `json:"system_id"`
+	ShipJumps int `json:"ship_jumps"`
+}
+
+// KillSubscriber receives the system kills from every successful fetch.
+type KillSubscriber func(kills []EsiSystemKills)
+
 // KillDataUpdater manages the background fetching service.
 type KillDataUpdater struct {
-	esiClient *ESIClient
-	filePath  string
+	esiClient   *ESIClient
+	filePath    string
+	jumpsPath   string
+	store       *Store
+	subscribers []KillSubscriber
+
+	mu    sync.RWMutex
+	kills map[int]EsiSystemKills
+	jumps map[int]int // nil until jump data has been fetched
 }
 
-// NewKillDataUpdater creates a new updater service.
-func NewKillDataUpdater(client *ESIClient, filePath string) *KillDataUpdater {
-	return &KillDataUpdater{
+// NewKillDataUpdater creates a new updater service. The latest kills and jumps
+// are kept in memory for the bot, starting from whatever their files hold, and
+// saved back to the files after each fetch. Both are also added to the store's
+// per-system histories.
+func NewKillDataUpdater(client *ESIClient, filePath, jumpsPath string, store *Store) *KillDataUpdater {
+	u := &KillDataUpdater{
 		esiClient: client,
 		filePath:  filePath,
+		jumpsPath: jumpsPath,
+		store:     store,
+		kills:     make(map[int]EsiSystemKills),
+	}
+
+	var kills []EsiSystemKills
+	if err := loadJSON(filePath, &kills); err != nil {
+		log.Printf("[UPDATER] WARN: %v", err)
+	}
+	u.setKills(kills)
+
+	var jumps []EsiSystemJumps
+	if err := loadJSON(jumpsPath, &jumps); err != nil {
+		log.Printf("[UPDATER] WARN: %v", err)
+	} else if jumps != nil {
+		u.setJumps(jumpsBySystem(jumps))
+	}
+	return u
+}
+
+// Kills returns a copy of the latest kills per system.
+func (u *KillDataUpdater) Kills() map[int]EsiSystemKills {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	kills := make(map[int]EsiSystemKills, len(u.kills))
+	for id, k := range u.kills {
+		kills[id] = k
+	}
+	return kills
+}
+
+// Jumps returns a copy of the latest ship jumps per system, or nil when no jump
+// data has been fetched yet, so traffic is left out rather than shown as zero.
+func (u *KillDataUpdater) Jumps() map[int]int {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	if u.jumps == nil {
+		return nil
+	}
+	jumps := make(map[int]int, len(u.jumps))
+	for id, j := range u.jumps {
+		jumps[id] = j
+	}
+	return jumps
+}
+
+func (u *KillDataUpdater) setKills(kills []EsiSystemKills) {
+	killMap := make(map[int]EsiSystemKills, len(kills))
+	for _, k := range kills {
+		killMap[k.SystemID] = k
+	}
+	u.mu.Lock()
+	u.kills = killMap
+	u.mu.Unlock()
+}
+
+func (u *KillDataUpdater) setJumps(jumpMap map[int]int) {
+	u.mu.Lock()
+	u.jumps = jumpMap
+	u.mu.Unlock()
+}
+
+// jumpsBySystem maps each system to its ship jumps.
+func jumpsBySystem(jumps []EsiSystemJumps) map[int]int {
+	jumpMap := make(map[int]int, len(jumps))
+	for _, j := range jumps {
+		jumpMap[j.SystemID] = j.ShipJumps
 	}
+	return jumpMap
+}
+
+// Subscribe registers fn to be called after every fetch. Call it before Start.
+func (u *KillDataUpdater) Subscribe(fn KillSubscriber) {
+	u.subscribers = append(u.subscribers, fn)
 }
 
 // Start launches the background updater. Run this as a goroutine.
@@ -43,7 +141,8 @@ func (u *KillDataUpdater) Start(wg *sync.WaitGroup, quit chan struct{}) {
 	}
 }
 
-// fetchAndSave gets the data from ESI and atomically writes it to the local file.
+// fetchAndSave gets the kill and jump data from ESI, keeps it in memory,
+// atomically writes each to its local file and records both in their histories.
 func (u *KillDataUpdater) fetchAndSave() {
 	log.Println("[UPDATER] Fetching latest system kill data from ESI...")
 	kills, err := u.esiClient.GetSystemKills()
@@ -51,30 +150,76 @@ func (u *KillDataUpdater) fetchAndSave() {
 		log.Printf("[UPDATER] ERROR: Failed to fetch kills from ESI: %v", err)
 		return
 	}
+	now := time.Now()
+	u.setKills(kills)
+	if err := u.saveJSON(u.filePath, kills); err != nil {
+		log.Printf("[UPDATER] ERROR: %v", err)
+	} else {
+		log.Printf("[UPDATER] ✅ Successfully saved kill data to %s.", u.filePath)
+	}
+	shipKills := make(map[int]int, len(kills))
+	for _, k := range kills {
+		shipKills[k.SystemID] = k.ShipKills
+	}
+	if err := u.store.AddKillReadings(now, shipKills, trendWindow); err != nil {
+		log.Printf("[UPDATER] ERROR: Failed to record kill history: %v", err)
+	}
+
+	// jumps are extra: a failure here still leaves the kills in place
+	var jumps []EsiSystemJumps
+	if err := esiGetJSON("/universe/system_jumps/", &jumps); err != nil {
+		log.Printf("[UPDATER] WARN: Failed to fetch jumps from ESI: %v", err)
+	} else {
+		jumpMap := jumpsBySystem(jumps)
+		u.setJumps(jumpMap)
+		if err := u.saveJSON(u.jumpsPath, jumps); err != nil {
+			log.Printf("[UPDATER] ERROR: %v", err)
+		} else {
+			log.Printf("[UPDATER] ✅ Successfully saved jump data to %s.", u.jumpsPath)
+		}
+		if err := u.store.AddJumpReadings(now, jumpMap, trendWindow); err != nil {
+			log.Printf("[UPDATER] ERROR: Failed to record jump history: %v", err)
+		}
+	}
+
+	for _, fn := range u.subscribers {
+		fn(kills)
+	}
+}
+
+// loadJSON reads a file saved by saveJSON. A missing file is not an error and
+// leaves target untouched.
+func loadJSON(filePath string, target interface{}) error {
+	b, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read '%s': %w", filePath, err)
+	}
+	if err := json.Unmarshal(b, target); err != nil {
+		return fmt.Errorf("failed to parse '%s': %w", filePath, err)
+	}
+	return nil
+}
 
-	jsonData, err := json.Marshal(kills)
+// saveJSON writes data to filePath as JSON, atomically.
+func (u *KillDataUpdater) saveJSON(filePath string, data interface{}) error {
+	jsonData, err := json.Marshal(data)
 	if err != nil {
-		log.Printf("[UPDATER] ERROR: Failed to convert kills to JSON: %v", err)
-		return
+		return fmt.Errorf("failed to convert data to JSON: %w", err)
 	}
 
-	// --- PERFORMANCE & SAFETY IMPROVEMENT ---
 	// Write to a temporary file first.
-	tempFilePath := u.filePath + ".tmp"
-	err = os.WriteFile(tempFilePath, jsonData, 0644)
-	if err != nil {
-		log.Printf("[UPDATER] ERROR: Failed to write to temporary file '%s': %v", tempFilePath, err)
-		return
+	tempFilePath := filePath + ".tmp"
+	if err := os.WriteFile(tempFilePath, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write to temporary file '%s': %w", tempFilePath, err)
 	}
 
 	// Atomically rename the temporary file to the final destination.
 	// This is an instant operation and prevents file corruption.
-	err = os.Rename(tempFilePath, u.filePath)
-	if err != nil {
-		log.Printf("[UPDATER] ERROR: Failed to rename temp file to '%s': %v", u.filePath, err)
-		return
+	if err := os.Rename(tempFilePath, filePath); err != nil {
+		return fmt.Errorf("failed to rename temp file to '%s': %w", filePath, err)
 	}
-	// --- END IMPROVEMENT ---
-
-	log.Printf("[UPDATER] ✅ Successfully saved kill data to %s.", u.filePath)
+	return nil
 }
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// metresPerLightYear converts ESI coordinates (metres) to light years.
+const metresPerLightYear = 9.4607304725808e15
+
+// Position is a system's location in space, in metres, as reported by ESI.
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// LightYearsTo returns the straight-line distance between two positions in light years.
+func (p Position) LightYearsTo(o Position) float64 {
+	dx, dy, dz := p.X-o.X, p.Y-o.Y, p.Z-o.Z
+	return math.Sqrt(dx*dx+dy*dy+dz*dz) / metresPerLightYear
+}
+
+// SystemPositions holds solar system coordinates. Most ship with the bot in
+// mapSolarSystems.csv; any system missing from it is fetched from ESI once by
+// Fill and kept in the store.
+type SystemPositions struct {
+	mu        sync.RWMutex
+	store     *Store
+	positions map[int]Position
+}
+
+// LoadSystemPositions reads the coordinates shipped in filename, then adds the
+// ones earlier fills saved in the store. A missing file or empty store is not
+// an error; Fill fetches whatever is left.
+func LoadSystemPositions(store *Store, filename string) *SystemPositions {
+	sp := &SystemPositions{store: store, positions: make(map[int]Position)}
+
+	shipped, err := loadPositionsCSV(filename)
+	if err != nil {
+		log.Printf("%s Could not read %s: %v", logWarn, filename, err)
+	}
+	for id, pos := range shipped {
+		sp.positions[id] = pos
+	}
+
+	saved, err := store.SystemPositions()
+	if err != nil {
+		log.Printf("%s Could not read saved system positions: %v", logWarn, err)
+	}
+	for id, pos := range saved {
+		sp.positions[id] = pos
+	}
+	log.Printf("%s Loaded positions for %d systems (%d from %s).", logSuccess, len(sp.positions), len(shipped), filename)
+	return sp
+}
+
+// loadPositionsCSV reads a solarSystemID,x,y,z CSV as written by tools/system-positions.
+func loadPositionsCSV(filename string) (map[int]Position, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV data: %w", err)
+	}
+
+	positions := make(map[int]Position, len(records))
+	for i, rec := range records {
+		if i == 0 || len(rec) < 4 {
+			continue
+		}
+		id, err := strconv.Atoi(rec[0])
+		if err != nil {
+			continue
+		}
+		var pos Position
+		var errX, errY, errZ error
+		pos.X, errX = strconv.ParseFloat(rec[1], 64)
+		pos.Y, errY = strconv.ParseFloat(rec[2], 64)
+		pos.Z, errZ = strconv.ParseFloat(rec[3], 64)
+		if errX != nil || errY != nil || errZ != nil {
+			log.Printf("Invalid position at row %d of %s", i+1, filename)
+			continue
+		}
+		positions[id] = pos
+	}
+	return positions, nil
+}
+
+// Get returns the position of a system.
+func (sp *SystemPositions) Get(systemID int) (Position, bool) {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+	pos, ok := sp.positions[systemID]
+	return pos, ok
+}
+
+// All returns a copy of every known position.
+func (sp *SystemPositions) All() map[int]Position {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+	all := make(map[int]Position, len(sp.positions))
+	for id, pos := range sp.positions {
+		all[id] = pos
+	}
+	return all
+}
+
+// positionSaveBatch is how many fetched positions Fill collects before saving,
+// so an interrupted fill keeps most of its progress.
+const positionSaveBatch = 500
+
+// Fill fetches the positions of any of the given systems that neither shipped
+// nor were saved before from ESI /universe/systems/{id}/, and saves them to the
+// store. Run this as a goroutine.
+func (sp *SystemPositions) Fill(systemIDs []int) {
+	var missing []int
+	sp.mu.RLock()
+	for _, id := range systemIDs {
+		if _, ok := sp.positions[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	sp.mu.RUnlock()
+	if len(missing) == 0 {
+		return
+	}
+
+	log.Printf("[POSITIONS] Fetching coordinates for %d systems from ESI...", len(missing))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var pendingMu sync.Mutex
+	pending := make(map[int]Position)
+	saved := 0
+	flush := func() {
+		// pendingMu must be held.
+		if len(pending) == 0 {
+			return
+		}
+		if err := sp.store.SaveSystemPositions(pending); err != nil {
+			log.Printf("[POSITIONS] ERROR: %v", err)
+			return
+		}
+		saved += len(pending)
+		pending = make(map[int]Position)
+	}
+
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				var sys struct {
+					Position Position `json:"position"`
+				}
+				if err := esiGetJSON(fmt.Sprintf("/universe/systems/%d/", id), &sys); err != nil {
+					log.Printf("[POSITIONS] WARN: failed to fetch system %d: %v", id, err)
+					continue
+				}
+				sp.mu.Lock()
+				sp.positions[id] = sys.Position
+				sp.mu.Unlock()
+
+				pendingMu.Lock()
+				pending[id] = sys.Position
+				if len(pending) >= positionSaveBatch {
+					flush()
+				}
+				pendingMu.Unlock()
+			}
+		}()
+	}
+	for _, id := range missing {
+		jobs <- id
+	}
+	close(jobs)
+	wg.Wait()
+
+	pendingMu.Lock()
+	flush()
+	pendingMu.Unlock()
+	log.Printf("[POSITIONS] ✅ Saved positions for %d new systems.", saved)
+}
+
+// loadSystemCacheIDs returns every system ID listed in system_cache.json.
+func loadSystemCacheIDs(filePath string) ([]int, error) {
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(raw))
+	for key := range raw {
+		if id, err := strconv.Atoi(key); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
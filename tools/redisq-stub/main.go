@@ -0,0 +1,184 @@
+// Command redisq-stub serves recorded zKillboard RedisQ responses so the bot's
+// kill feed can be run without zKillboard. Record responses with, e.g.,
+//
+//	curl -s 'https://zkillredisq.stream/listen.php?queueID=test' >> kills.jsonl
+//
+// then start the stub and point the bot at it:
+//
+//	go run ./tools/redisq-stub -file kills.jsonl -retime
+//	ZKILL_QUEUE_ID=test ZKILL_REDISQ_URL=http://localhost:8090/listen.php
+//
+// With -retime, packages that only link to ESI (zkb.href) have the link
+// pointed at the stub, which fetches the killmail from ESI and retimes it.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+func main() {
+	file := flag.String("file", "kills.jsonl", "recorded RedisQ responses, one JSON object per line")
+	addr := flag.String("addr", ":8090", "address to listen on")
+	interval := flag.Duration("interval", 2*time.Second, "delay between killmails")
+	loop := flag.Bool("loop", false, "start over after the last killmail")
+	retime := flag.Bool("retime", false, "set each killmail's time to now so it falls inside the bot's window")
+	flag.Parse()
+
+	packages, err := loadPackages(*file)
+	if err != nil {
+		log.Fatalf("[STUB] FATAL: %v", err)
+	}
+	log.Printf("[STUB] Loaded %d killmails from %s.", len(packages), *file)
+
+	var mu sync.Mutex
+	next := 0
+	last := time.Time{}
+
+	http.HandleFunc("/listen.php", func(w http.ResponseWriter, r *http.Request) {
+		ttw, err := strconv.Atoi(r.URL.Query().Get("ttw"))
+		if err != nil || ttw <= 0 {
+			ttw = 10
+		}
+
+		mu.Lock()
+		if *loop && next == len(packages) {
+			next = 0
+		}
+		var pkg map[string]interface{}
+		if next < len(packages) {
+			pkg = packages[next]
+			next++
+		}
+		wait := time.Until(last.Add(*interval))
+		last = time.Now().Add(max(wait, 0))
+		mu.Unlock()
+
+		if pkg == nil {
+			// like RedisQ, hold the request for the full wait when there is nothing to send
+			time.Sleep(time.Duration(ttw) * time.Second)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"package":null}`))
+			return
+		}
+		time.Sleep(wait)
+		if *retime {
+			pkg = retimed(pkg, "http://"+r.Host)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"package": pkg})
+	})
+
+	// Killmails behind a rewritten href: fetch the original and retime it.
+	http.HandleFunc("/killmail", func(w http.ResponseWriter, r *http.Request) {
+		km, err := fetchKillmail(r.URL.Query().Get("href"))
+		if err != nil {
+			log.Printf("[STUB] WARN: %v", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		km["killmail_time"] = time.Now().UTC().Format(time.RFC3339)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(km)
+	})
+
+	log.Printf("[STUB] Serving RedisQ on %s/listen.php", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+// retimed returns a copy of pkg with the killmail time set to now. A package
+// that only links to ESI gets its link pointed at the stub's /killmail on
+// base, which retimes the killmail as it is fetched. The loaded packages are
+// shared by every request, so they are never changed in place.
+func retimed(pkg map[string]interface{}, base string) map[string]interface{} {
+	pkgCopy := make(map[string]interface{}, len(pkg))
+	for k, v := range pkg {
+		pkgCopy[k] = v
+	}
+
+	if km, ok := pkg["killmail"].(map[string]interface{}); ok {
+		kmCopy := make(map[string]interface{}, len(km))
+		for k, v := range km {
+			kmCopy[k] = v
+		}
+		kmCopy["killmail_time"] = time.Now().UTC().Format(time.RFC3339)
+		pkgCopy["killmail"] = kmCopy
+		return pkgCopy
+	}
+
+	zkb, ok := pkg["zkb"].(map[string]interface{})
+	if !ok {
+		return pkg
+	}
+	href, ok := zkb["href"].(string)
+	if !ok || href == "" {
+		return pkg
+	}
+	zkbCopy := make(map[string]interface{}, len(zkb))
+	for k, v := range zkb {
+		zkbCopy[k] = v
+	}
+	zkbCopy["href"] = base + "/killmail?href=" + url.QueryEscape(href)
+	pkgCopy["zkb"] = zkbCopy
+	return pkgCopy
+}
+
+// fetchKillmail downloads a killmail from its ESI link.
+func fetchKillmail(href string) (map[string]interface{}, error) {
+	if href == "" {
+		return nil, fmt.Errorf("missing href")
+	}
+	resp, err := http.Get(href)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", href, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: status %d", href, resp.StatusCode)
+	}
+	var km map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&km); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", href, err)
+	}
+	return km, nil
+}
+
+// loadPackages reads the packages from a file of RedisQ responses, skipping
+// empty ones. Lines may also hold a bare package.
+func loadPackages(path string) ([]map[string]interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var packages []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, err
+		}
+		if pkg, ok := line["package"]; ok {
+			if pkg, ok := pkg.(map[string]interface{}); ok {
+				packages = append(packages, pkg)
+			}
+			continue
+		}
+		packages = append(packages, line)
+	}
+	return packages, scanner.Err()
+}
@@ -0,0 +1,117 @@
+// Command system-positions writes the coordinates of every solar system to
+// mapSolarSystems.csv, which the bot loads for jump-drive routing instead of
+// asking ESI for each system. Regenerate it after new systems are added:
+//
+//	go run ./tools/system-positions -out mapSolarSystems.csv
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const esiBaseURL = "https://esi.evetech.net/latest"
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+type position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+func main() {
+	out := flag.String("out", "mapSolarSystems.csv", "CSV file to write")
+	workers := flag.Int("workers", 8, "concurrent ESI requests")
+	flag.Parse()
+
+	var ids []int
+	if err := getJSON("/universe/systems/", &ids); err != nil {
+		log.Fatalf("[POSITIONS] FATAL: listing systems: %v", err)
+	}
+	log.Printf("[POSITIONS] Fetching coordinates for %d systems...", len(ids))
+
+	var mu sync.Mutex
+	positions := make(map[int]position, len(ids))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < *workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				var sys struct {
+					Position position `json:"position"`
+				}
+				if err := getJSON(fmt.Sprintf("/universe/systems/%d/", id), &sys); err != nil {
+					log.Printf("[POSITIONS] WARN: failed to fetch system %d: %v", id, err)
+					continue
+				}
+				mu.Lock()
+				positions[id] = sys.Position
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, id := range ids {
+		jobs <- id
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := writeCSV(*out, positions); err != nil {
+		log.Fatalf("[POSITIONS] FATAL: %v", err)
+	}
+	log.Printf("[POSITIONS] ✅ Wrote %d of %d systems to %s.", len(positions), len(ids), *out)
+}
+
+// getJSON performs a GET against ESI and decodes the JSON response.
+func getJSON(endpoint string, target interface{}) error {
+	resp, err := httpClient.Get(esiBaseURL + endpoint)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ESI returned non-200 status: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// writeCSV saves positions sorted by system ID, in metres as ESI reports them.
+func writeCSV(filename string, positions map[int]position) error {
+	ids := make([]int, 0, len(positions))
+	for id := range positions {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write([]string{"solarSystemID", "x", "y", "z"})
+	for _, id := range ids {
+		p := positions[id]
+		w.Write([]string{
+			strconv.Itoa(id),
+			strconv.FormatFloat(p.X, 'f', -1, 64),
+			strconv.FormatFloat(p.Y, 'f', -1, 64),
+			strconv.FormatFloat(p.Z, 'f', -1, 64),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// exactTourLimit is the most stops PlanTour solves exactly (Held-Karp is
+// O(2^n * n^2)); larger sets fall back to nearest neighbour plus 2-opt.
+const exactTourLimit = 12
+
+// TourResult is a visiting order over a set of systems and the full route that follows it.
+type TourResult struct {
+	Order   []int // systems in visiting order, starting with the start system
+	Legs    [][]int
+	Path    []int
+	Optimal bool // false when the order came from the heuristic
+}
+
+// PlanTour finds the cheapest order to visit every stop from startID under opts,
+// returning to the start when roundTrip is set. It fails if any stop cannot be reached.
+func PlanTour(graph *Graph, startID int, stops []int, esiClient *ESIClient, opts RouteOptions, roundTrip bool) (*TourResult, error) {
+	nodes := []int{startID}
+	seen := map[int]bool{startID: true}
+	for _, id := range stops {
+		if !seen[id] {
+			seen[id] = true
+			nodes = append(nodes, id)
+		}
+	}
+
+	now := time.Now()
+	cost := func(e Edge) float64 {
+		c, _ := routeEdgeCost(e, esiClient, opts, now)
+		return c
+	}
+
+	// One full search per node gives the cost matrix and the paths to stitch together.
+	dist := make([][]float64, len(nodes))
+	trees := make([]map[int]int, len(nodes))
+	for i, from := range nodes {
+		costs, parents := ShortestPathTree(graph, from, cost)
+		trees[i] = parents
+		dist[i] = make([]float64, len(nodes))
+		for j, to := range nodes {
+			c, ok := costs[to]
+			if !ok {
+				return nil, fmt.Errorf("system %d cannot be reached from %d", to, from)
+			}
+			dist[i][j] = c
+		}
+	}
+
+	var order []int
+	optimal := len(nodes)-1 <= exactTourLimit
+	if optimal {
+		order = heldKarpOrder(dist, roundTrip)
+	} else {
+		order = twoOpt(nearestNeighbourOrder(dist), dist, roundTrip)
+	}
+	if roundTrip {
+		order = append(order, 0)
+	}
+
+	result := &TourResult{Optimal: optimal, Path: []int{startID}}
+	for n, idx := range order {
+		result.Order = append(result.Order, nodes[idx])
+		if n == 0 {
+			continue
+		}
+		prev := order[n-1]
+		leg := buildPath(trees[prev], nodes[prev], nodes[idx])
+		if leg == nil {
+			return nil, fmt.Errorf("no path between %d and %d", nodes[prev], nodes[idx])
+		}
+		result.Legs = append(result.Legs, leg)
+		result.Path = append(result.Path, leg[1:]...)
+	}
+	return result, nil
+}
+
+// tourCost sums the cost of visiting nodes in order, optionally closing the loop.
+func tourCost(order []int, dist [][]float64, roundTrip bool) float64 {
+	total := 0.0
+	for n := 1; n < len(order); n++ {
+		total += dist[order[n-1]][order[n]]
+	}
+	if roundTrip && len(order) > 1 {
+		total += dist[order[len(order)-1]][order[0]]
+	}
+	return total
+}
+
+// heldKarpOrder solves the visiting order exactly by dynamic programming over
+// subsets of stops. Node 0 is the fixed start.
+func heldKarpOrder(dist [][]float64, roundTrip bool) []int {
+	n := len(dist)
+	if n <= 2 {
+		order := make([]int, n)
+		for i := range order {
+			order[i] = i
+		}
+		return order
+	}
+
+	// best[mask][j] is the cheapest way to leave the start, visit the stops in
+	// mask (bit k = node k+1) and finish at node j+1.
+	stops := n - 1
+	full := 1<<stops - 1
+	best := make([][]float64, full+1)
+	prev := make([][]int, full+1)
+	for mask := range best {
+		best[mask] = make([]float64, stops)
+		prev[mask] = make([]int, stops)
+		for j := range best[mask] {
+			best[mask][j] = math.Inf(1)
+			prev[mask][j] = -1
+		}
+	}
+	for j := 0; j < stops; j++ {
+		best[1<<j][j] = dist[0][j+1]
+	}
+
+	for mask := 1; mask <= full; mask++ {
+		for j := 0; j < stops; j++ {
+			if mask&(1<<j) == 0 || math.IsInf(best[mask][j], 1) {
+				continue
+			}
+			for k := 0; k < stops; k++ {
+				if mask&(1<<k) != 0 {
+					continue
+				}
+				next := mask | 1<<k
+				if c := best[mask][j] + dist[j+1][k+1]; c < best[next][k] {
+					best[next][k], prev[next][k] = c, j
+				}
+			}
+		}
+	}
+
+	last, lowest := 0, math.Inf(1)
+	for j := 0; j < stops; j++ {
+		c := best[full][j]
+		if roundTrip {
+			c += dist[j+1][0]
+		}
+		if c < lowest {
+			last, lowest = j, c
+		}
+	}
+
+	order := make([]int, n)
+	mask := full
+	for pos := n - 1; pos > 0; pos-- {
+		order[pos] = last + 1
+		last, mask = prev[mask][last], mask&^(1<<last)
+	}
+	return order
+}
+
+// nearestNeighbourOrder greedily visits the cheapest unvisited node next.
+func nearestNeighbourOrder(dist [][]float64) []int {
+	order := []int{0}
+	visited := make([]bool, len(dist))
+	visited[0] = true
+	for len(order) < len(dist) {
+		current, next, lowest := order[len(order)-1], -1, math.Inf(1)
+		for j := range dist {
+			if !visited[j] && dist[current][j] < lowest {
+				next, lowest = j, dist[current][j]
+			}
+		}
+		visited[next] = true
+		order = append(order, next)
+	}
+	return order
+}
+
+// twoOpt improves an order by reversing segments while that lowers the total
+// cost. The start stays fixed. Costs may be asymmetric, so each candidate is
+// re-scored in full.
+func twoOpt(order []int, dist [][]float64, roundTrip bool) []int {
+	bestCost := tourCost(order, dist, roundTrip)
+	for improved := true; improved; {
+		improved = false
+		for i := 1; i < len(order)-1; i++ {
+			for k := i + 1; k < len(order); k++ {
+				reverseInts(order[i : k+1])
+				if c := tourCost(order, dist, roundTrip); c < bestCost {
+					bestCost, improved = c, true
+				} else {
+					reverseInts(order[i : k+1])
+				}
+			}
+		}
+	}
+	return order
+}
+
+func reverseInts(s []int) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
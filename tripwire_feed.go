@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TripwireChangeKind says what happened to a signature or wormhole between two polls.
+type TripwireChangeKind string
+
+const (
+	SignatureAdded   TripwireChangeKind = "signature_added"
+	SignatureRemoved TripwireChangeKind = "signature_removed"
+	SignatureUpdated TripwireChangeKind = "signature_updated"
+	WormholeAdded    TripwireChangeKind = "wormhole_added"
+	WormholeRemoved  TripwireChangeKind = "wormhole_removed"
+	WormholeUpdated  TripwireChangeKind = "wormhole_updated"
+)
+
+// TripwireChange is one entry in the change log. For removals the fields hold
+// the last known state; for updates Previous holds the state before.
+type TripwireChange struct {
+	Kind              TripwireChangeKind `json:"kind"`
+	Key               string             `json:"key"`
+	Signature         *TripwireSignature `json:"signature,omitempty"`
+	PreviousSignature *TripwireSignature `json:"previous_signature,omitempty"`
+	Wormhole          *TripwireWormhole  `json:"wormhole,omitempty"`
+	PreviousWormhole  *TripwireWormhole  `json:"previous_wormhole,omitempty"`
+}
+
+// TripwireDiff is everything that changed in one poll.
+type TripwireDiff struct {
+	At      time.Time        `json:"at"`
+	Changes []TripwireChange `json:"changes"`
+}
+
+// TripwireSubscriber receives every new snapshot together with what changed since the last one.
+type TripwireSubscriber func(data *TripwireData, diff TripwireDiff)
+
+// tripwireWatchInterval is how often the fetcher's cache file is checked for a new poll.
+const tripwireWatchInterval = 15 * time.Second
+
+// TripwireFeed publishes each poll the Tripwire fetcher writes to its cache
+// file. It saves the snapshot, works out what changed and passes both on to
+// its subscribers.
+type TripwireFeed struct {
+	sourcePath string // the fetcher's cache file
+	filePath   string // where published snapshots are saved
+	sourceMod  time.Time
+
+	mu          sync.Mutex
+	latest      *TripwireData
+	subscribers []TripwireSubscriber
+}
+
+// NewTripwireFeed creates a feed that watches the fetcher's cache at sourcePath
+// and saves snapshots to filePath. initial is the snapshot loaded at startup,
+// if any; the first poll is diffed against it.
+func NewTripwireFeed(sourcePath, filePath string, initial *TripwireData) *TripwireFeed {
+	return &TripwireFeed{sourcePath: sourcePath, filePath: filePath, latest: initial}
+}
+
+// Start publishes the fetcher's cache file whenever it changes, until quit is
+// closed. Run this as a goroutine.
+func (f *TripwireFeed) Start(wg *sync.WaitGroup, quit chan struct{}) {
+	defer wg.Done()
+	log.Printf("[TRIPWIRE] Watching %s for new polls...", f.sourcePath)
+
+	ticker := time.NewTicker(tripwireWatchInterval)
+	defer ticker.Stop()
+
+	f.checkSource() // Run once immediately on startup.
+
+	for {
+		select {
+		case <-ticker.C:
+			f.checkSource()
+		case <-quit:
+			log.Println("[TRIPWIRE] Shutdown signal received, exiting.")
+			return
+		}
+	}
+}
+
+// checkSource publishes the cache file if it was modified since it was last read.
+func (f *TripwireFeed) checkSource() {
+	info, err := os.Stat(f.sourcePath)
+	if err != nil {
+		log.Printf("[TRIPWIRE] WARN: %v", err)
+		return
+	}
+	if info.ModTime().Equal(f.sourceMod) {
+		return
+	}
+	data, err := loadTripwireData(f.sourcePath)
+	if err != nil {
+		// Most likely caught mid-write; the next check tries again.
+		log.Printf("[TRIPWIRE] WARN: could not read %s: %v", f.sourcePath, err)
+		return
+	}
+	if data == nil {
+		return // emptied for rewriting; not a poll
+	}
+	f.sourceMod = info.ModTime()
+	f.Publish(data)
+}
+
+// Subscribe registers fn to be called after every poll. Subscribers run in
+// order on the publishing goroutine, so slow work should be handed off.
+func (f *TripwireFeed) Subscribe(fn TripwireSubscriber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subscribers = append(f.subscribers, fn)
+}
+
+// Latest returns the most recent snapshot, or nil before the first one.
+func (f *TripwireFeed) Latest() *TripwireData {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.latest
+}
+
+// Publish saves a new snapshot, diffs it against the previous one and notifies subscribers.
+func (f *TripwireFeed) Publish(data *TripwireData) TripwireDiff {
+	f.mu.Lock()
+	previous := f.latest
+	f.latest = data
+	subscribers := append([]TripwireSubscriber(nil), f.subscribers...)
+	f.mu.Unlock()
+
+	diff := TripwireDiff{At: time.Now(), Changes: DiffTripwire(previous, data)}
+	if len(diff.Changes) > 0 {
+		log.Printf("[TRIPWIRE] %d changes since the last poll.", len(diff.Changes))
+	}
+
+	if err := f.save(data); err != nil {
+		log.Printf("[TRIPWIRE] ERROR: %v", err)
+	}
+	for _, fn := range subscribers {
+		fn(data, diff)
+	}
+	return diff
+}
+
+// save atomically writes the snapshot via a temporary file and rename.
+func (f *TripwireFeed) save(data *TripwireData) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to convert tripwire data to JSON: %w", err)
+	}
+	tempFilePath := f.filePath + ".tmp"
+	if err := os.WriteFile(tempFilePath, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write temporary file '%s': %w", tempFilePath, err)
+	}
+	if err := os.Rename(tempFilePath, f.filePath); err != nil {
+		return fmt.Errorf("failed to rename temp file to '%s': %w", f.filePath, err)
+	}
+	return nil
+}
+
+// DiffTripwire lists the signatures and wormholes added, removed or changed
+// between two snapshots, sorted by key. A nil snapshot counts as empty.
+func DiffTripwire(before, after *TripwireData) []TripwireChange {
+	if before == nil {
+		before = &TripwireData{}
+	}
+	if after == nil {
+		after = &TripwireData{}
+	}
+
+	var changes []TripwireChange
+	for key, sig := range after.Signatures {
+		sig := sig
+		old, ok := before.Signatures[key]
+		switch {
+		case !ok:
+			changes = append(changes, TripwireChange{Kind: SignatureAdded, Key: key, Signature: &sig})
+		case old.ModifiedTime != sig.ModifiedTime:
+			changes = append(changes, TripwireChange{Kind: SignatureUpdated, Key: key, Signature: &sig, PreviousSignature: &old})
+		}
+	}
+	for key, sig := range before.Signatures {
+		sig := sig
+		if _, ok := after.Signatures[key]; !ok {
+			changes = append(changes, TripwireChange{Kind: SignatureRemoved, Key: key, Signature: &sig})
+		}
+	}
+
+	for key, wh := range after.Wormholes {
+		wh := wh
+		old, ok := before.Wormholes[key]
+		switch {
+		case !ok:
+			changes = append(changes, TripwireChange{Kind: WormholeAdded, Key: key, Wormhole: &wh})
+		case old.Type != wh.Type || old.Life != wh.Life || old.Mass != wh.Mass:
+			changes = append(changes, TripwireChange{Kind: WormholeUpdated, Key: key, Wormhole: &wh, PreviousWormhole: &old})
+		}
+	}
+	for key, wh := range before.Wormholes {
+		wh := wh
+		if _, ok := after.Wormholes[key]; !ok {
+			changes = append(changes, TripwireChange{Kind: WormholeRemoved, Key: key, Wormhole: &wh})
+		}
+	}
+
+	sort.Slice(changes, func(a, b int) bool {
+		if changes[a].Kind != changes[b].Kind {
+			return changes[a].Kind < changes[b].Kind
+		}
+		return changes[a].Key < changes[b].Key
+	})
+	return changes
+}
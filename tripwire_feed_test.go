@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTripwireFeedPublishesCacheChanges(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "tripwire_data.json")
+	feed := NewTripwireFeed(source, filepath.Join(dir, "tripwire_latest.json"), nil)
+
+	var diffs []TripwireDiff
+	feed.Subscribe(func(_ *TripwireData, diff TripwireDiff) { diffs = append(diffs, diff) })
+
+	write := func(body string, mod time.Time) {
+		t.Helper()
+		if err := os.WriteFile(source, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(source, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+
+	write(`{"signatures":{"1":{"id":"1","systemID":"31000005"}},"wormholes":{}}`, now)
+	feed.checkSource()
+	feed.checkSource() // unchanged, not published again
+	if len(diffs) != 1 || len(diffs[0].Changes) != 1 || diffs[0].Changes[0].Kind != SignatureAdded {
+		t.Fatalf("after first poll got %+v, want one added signature", diffs)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tripwire_latest.json")); err != nil {
+		t.Errorf("snapshot not saved: %v", err)
+	}
+
+	write(`{"signatures":{},"wormholes":{}}`, now.Add(time.Minute))
+	feed.checkSource()
+	if len(diffs) != 2 || len(diffs[1].Changes) != 1 || diffs[1].Changes[0].Kind != SignatureRemoved {
+		t.Fatalf("after second poll got %+v, want one removed signature", diffs)
+	}
+}
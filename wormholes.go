@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// shipMasses maps the /route ship option to a representative hull mass in kg,
+// erring on the heavy side of each class: the heaviest freighter (Charon) and
+// dreadnought (Moros) for the two largest.
+var shipMasses = map[string]float64{
+	"frigate":    2_000_000,
+	"cruiser":    20_000_000,
+	"battleship": 110_000_000,
+	"freighter":  960_000_000,
+	"capital":    1_300_000_000,
+}
+
+// Maximum single-jump mass in kg for each wormhole size.
+const (
+	jumpMassFrigate = 5_000_000
+	jumpMassSmall   = 62_000_000
+	jumpMassLarge   = 375_000_000
+	jumpMassFreight = 1_000_000_000
+	jumpMassCapital = 1_350_000_000
+)
+
+// wormholeJumpMass lists the max jump mass for known wormhole types. Types that
+// are missing, blank or K162 fall back to defaultWormholeJumpMass.
+var wormholeJumpMass = map[string]float64{
+	// Frigate holes
+	"E004": jumpMassFrigate, "L005": jumpMassFrigate, "Z006": jumpMassFrigate, "M001": jumpMassFrigate,
+	"C008": jumpMassFrigate, "G008": jumpMassFrigate, "Q003": jumpMassFrigate, "A009": jumpMassFrigate,
+	// C1 statics and holes into C1
+	"H121": jumpMassSmall, "C125": jumpMassSmall, "O883": jumpMassSmall, "M609": jumpMassSmall,
+	"L614": jumpMassSmall, "S804": jumpMassSmall, "N110": jumpMassSmall, "J244": jumpMassSmall,
+	"Z060": jumpMassSmall, "Z647": jumpMassSmall, "V301": jumpMassSmall, "P060": jumpMassSmall,
+	"Y790": jumpMassSmall, "Z971": jumpMassSmall, "Q317": jumpMassSmall,
+	// Freighter-capable k-space holes
+	"A641": jumpMassFreight, "R051": jumpMassFreight, "V283": jumpMassFreight, "N944": jumpMassFreight,
+	"S199": jumpMassFreight, "B449": jumpMassFreight, "Q063": jumpMassFreight,
+	// Capital-capable C5/C6 holes
+	"H296": jumpMassCapital, "V911": jumpMassCapital, "W237": jumpMassCapital, "V753": jumpMassCapital,
+	"N432": jumpMassCapital, "U574": jumpMassCapital, "Z142": jumpMassCapital, "Z457": jumpMassCapital,
+}
+
+// defaultWormholeJumpMass is assumed for unidentified holes: battleship-capable,
+// but not freighters or capitals.
+const defaultWormholeJumpMass = jumpMassLarge
+
+// shipSizeJumpMass maps EVE-Scout's max_ship_size classes to a max jump mass.
+var shipSizeJumpMass = map[string]float64{
+	"small":   jumpMassFrigate,
+	"medium":  jumpMassSmall,
+	"large":   jumpMassLarge,
+	"xlarge":  jumpMassFreight,
+	"capital": jumpMassCapital,
+}
+
+// jumpMassFor returns the max jump mass of a wormhole type.
+func jumpMassFor(whType string) float64 {
+	if m, ok := wormholeJumpMass[strings.ToUpper(whType)]; ok {
+		return m
+	}
+	return defaultWormholeJumpMass
+}
+
+// edgeJumpMass returns the max jump mass of a wormhole edge. A known type wins;
+// otherwise the reported max ship size is used, as for K162s out of Thera.
+func edgeJumpMass(e Edge) float64 {
+	if _, ok := wormholeJumpMass[strings.ToUpper(e.WormholeType)]; !ok {
+		if m, ok := shipSizeJumpMass[e.MaxShipSize]; ok {
+			return m
+		}
+	}
+	return jumpMassFor(e.WormholeType)
+}
+
+// eolWindow is the most time a wormhole can have left once it is flagged end of life.
+const eolWindow = 4 * time.Hour
+
+// lifeRemaining returns how long a wormhole has left. The bool is false when
+// neither an expiry time nor an end-of-life flag is known.
+func lifeRemaining(e Edge, now time.Time) (time.Duration, bool) {
+	var left time.Duration
+	known := false
+	if !e.ExpiresAt.IsZero() {
+		left, known = e.ExpiresAt.Sub(now), true
+	}
+	if e.Life == "critical" && (!known || left > eolWindow) {
+		left, known = eolWindow, true
+	}
+	return left, known
+}
+
+// shortDuration formats a duration as e.g. "45m" or "3h20m".
+func shortDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	if m := int(d.Minutes()) % 60; m != 0 {
+		return fmt.Sprintf("%dh%dm", int(d.Hours()), m)
+	}
+	return fmt.Sprintf("%dh", int(d.Hours()))
+}
+
+// edgeRestriction returns why a jump must not be taken under opts, or "" if it is allowed.
+func edgeRestriction(e Edge, opts RouteOptions, now time.Time) string {
+	if !e.IsWormhole() {
+		return ""
+	}
+	if opts.MinLifetime > 0 {
+		if left, known := lifeRemaining(e, now); known && left < opts.MinLifetime {
+			return fmt.Sprintf("~%s left, under %s", shortDuration(left), shortDuration(opts.MinLifetime))
+		}
+	}
+	if opts.Ship == "" {
+		return ""
+	}
+	if e.Mass == "critical" {
+		return "critical mass"
+	}
+	if shipMass, ok := shipMasses[opts.Ship]; ok && edgeJumpMass(e) < shipMass {
+		return fmt.Sprintf("too small for a %s", opts.Ship)
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEdgeRestrictionShipSize(t *testing.T) {
+	holes := []struct {
+		whType  string
+		allowed []string // ship classes that fit
+	}{
+		{"E004", []string{"frigate"}},                                                  // frigate hole
+		{"H121", []string{"frigate", "cruiser"}},                                       // into C1
+		{"K162", []string{"frigate", "cruiser", "battleship"}},                         // unknown size
+		{"A641", []string{"frigate", "cruiser", "battleship", "freighter"}},            // k-space, freighter-capable
+		{"H296", []string{"frigate", "cruiser", "battleship", "freighter", "capital"}}, // into C5
+	}
+	now := time.Now()
+	for _, hole := range holes {
+		fits := make(map[string]bool)
+		for _, ship := range hole.allowed {
+			fits[ship] = true
+		}
+		e := Edge{FromID: 1, ToID: 2, Kind: EdgeWormhole, WormholeType: hole.whType}
+		for ship := range shipMasses {
+			reason := edgeRestriction(e, RouteOptions{Ship: ship}, now)
+			if got := reason == ""; got != fits[ship] {
+				t.Errorf("%s through %s: allowed %v (%q), want %v", ship, hole.whType, got, reason, fits[ship])
+			}
+		}
+	}
+}
+
+func TestEdgeJumpMassFallsBackToShipSize(t *testing.T) {
+	e := Edge{Kind: EdgeThera, WormholeType: "K162", MaxShipSize: "capital"}
+	if got := edgeJumpMass(e); got != jumpMassCapital {
+		t.Errorf("K162 reported as capital: got %v, want %v", got, float64(jumpMassCapital))
+	}
+	e.WormholeType = "E004"
+	if got := edgeJumpMass(e); got != jumpMassFrigate {
+		t.Errorf("E004 reported as capital: got %v, want the type's %v", got, float64(jumpMassFrigate))
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// defaultRedisQURL is zKillboard's RedisQ endpoint. ZKILL_REDISQ_URL overrides
+// it, for example to point at a local server replaying recorded killmails.
+const defaultRedisQURL = "https://zkillredisq.stream/listen.php"
+
+// redisQWait is how many seconds RedisQ holds a request open waiting for a kill.
+const redisQWait = 10
+
+// killFeedPublishInterval is how often the rolling window is passed to subscribers.
+const killFeedPublishInterval = time.Minute
+
+// capsuleTypeIDs are the ship types counted as pod kills.
+var capsuleTypeIDs = map[int]bool{670: true, 33328: true}
+
+// Killmail is the part of a zKillboard killmail the bot uses.
+type Killmail struct {
+	KillID        int
+	SolarSystemID int
+	Time          time.Time
+	ShipTypeID    int
+	NPC           bool // the victim was an NPC
+}
+
+// redisQPackage is one RedisQ response. Package is null when no kill arrived
+// before the wait ran out. Newer packages leave out the killmail and link to
+// it on ESI instead.
+type redisQPackage struct {
+	Package *struct {
+		KillID   int             `json:"killID"`
+		Killmail *esiKillmail    `json:"killmail"`
+		Zkb      redisQZkbFields `json:"zkb"`
+	} `json:"package"`
+}
+
+type redisQZkbFields struct {
+	NPC  bool   `json:"npc"`
+	Href string `json:"href"`
+}
+
+type esiKillmail struct {
+	KillmailID    int       `json:"killmail_id"`
+	KillmailTime  time.Time `json:"killmail_time"`
+	SolarSystemID int       `json:"solar_system_id"`
+	Victim        struct {
+		ShipTypeID int `json:"ship_type_id"`
+	} `json:"victim"`
+}
+
+// KillFeed long-polls zKillboard's RedisQ for killmails as they happen and
+// keeps the ones from the last window in memory. Unlike ESI's hourly
+// system kills, its counts are up to date to within seconds.
+type KillFeed struct {
+	listenURL  string
+	window     time.Duration
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	kills       []Killmail // oldest first
+	seen        map[int]bool
+	changed     bool
+	subscribers []KillSubscriber
+}
+
+// NewKillFeed creates a feed that reads from the RedisQ endpoint at baseURL
+// with the given queue ID and remembers kills for window.
+func NewKillFeed(baseURL, queueID string, window time.Duration) (*KillFeed, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RedisQ URL '%s': %w", baseURL, err)
+	}
+	q := u.Query()
+	q.Set("queueID", queueID)
+	q.Set("ttw", fmt.Sprint(redisQWait))
+	u.RawQuery = q.Encode()
+
+	return &KillFeed{
+		listenURL:  u.String(),
+		window:     window,
+		httpClient: &http.Client{Timeout: (redisQWait + 15) * time.Second},
+		seen:       make(map[int]bool),
+	}, nil
+}
+
+// Subscribe registers fn to receive the window's kill counts whenever they
+// change, at most once per killFeedPublishInterval. Call it before Start.
+func (f *KillFeed) Subscribe(fn KillSubscriber) {
+	f.subscribers = append(f.subscribers, fn)
+}
+
+// Start polls RedisQ until quit is closed, backing off while it is unreachable.
+func (f *KillFeed) Start(wg *sync.WaitGroup, quit chan struct{}) {
+	defer wg.Done()
+	log.Println("[KILLFEED] Starting zKillboard RedisQ listener...")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-quit
+		cancel()
+	}()
+	go f.publishLoop(ctx)
+
+	backoff := time.Second
+	for ctx.Err() == nil {
+		km, err := f.poll(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("[KILLFEED] ERROR: %v (retrying in %s)", err, backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+			}
+			backoff = min(backoff*2, time.Minute)
+			continue
+		}
+		backoff = time.Second
+		if km != nil {
+			f.Add(*km)
+		}
+	}
+	log.Println("[KILLFEED] Shutdown signal received, exiting.")
+}
+
+// publishLoop hands the window's counts to subscribers whenever kills came in.
+func (f *KillFeed) publishLoop(ctx context.Context) {
+	ticker := time.NewTicker(killFeedPublishInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f.mu.Lock()
+			changed := f.changed
+			f.changed = false
+			f.mu.Unlock()
+			if !changed {
+				continue
+			}
+			counts := f.Counts()
+			for _, fn := range f.subscribers {
+				fn(counts)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// poll waits for the next killmail. It returns nil, nil when none arrived in time.
+func (f *KillFeed) poll(ctx context.Context) (*Killmail, error) {
+	var pkg redisQPackage
+	if err := f.getJSON(ctx, f.listenURL, &pkg); err != nil {
+		return nil, err
+	}
+	if pkg.Package == nil {
+		return nil, nil
+	}
+
+	km := pkg.Package.Killmail
+	if km == nil {
+		if pkg.Package.Zkb.Href == "" {
+			return nil, fmt.Errorf("kill %d has no killmail or ESI link", pkg.Package.KillID)
+		}
+		km = &esiKillmail{}
+		if err := f.getJSON(ctx, pkg.Package.Zkb.Href, km); err != nil {
+			return nil, fmt.Errorf("failed to fetch killmail %d: %w", pkg.Package.KillID, err)
+		}
+	}
+	return &Killmail{
+		KillID:        pkg.Package.KillID,
+		SolarSystemID: km.SolarSystemID,
+		Time:          km.KillmailTime,
+		ShipTypeID:    km.Victim.ShipTypeID,
+		NPC:           pkg.Package.Zkb.NPC,
+	}, nil
+}
+
+func (f *KillFeed) getJSON(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", esiUserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("api returned non-200 status: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode json response: %w", err)
+	}
+	return nil
+}
+
+// Add records a killmail. Repeats and kills older than the window are ignored.
+func (f *KillFeed) Add(km Killmail) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.prune(time.Now())
+	if f.seen[km.KillID] || time.Since(km.Time) > f.window {
+		return
+	}
+	f.seen[km.KillID] = true
+	f.changed = true
+
+	// kills usually arrive in order; keep the slice sorted when they do not
+	n := len(f.kills)
+	for n > 0 && f.kills[n-1].Time.After(km.Time) {
+		n--
+	}
+	f.kills = append(f.kills, Killmail{})
+	copy(f.kills[n+1:], f.kills[n:])
+	f.kills[n] = km
+}
+
+// prune drops kills that have left the window. Callers hold f.mu.
+func (f *KillFeed) prune(now time.Time) {
+	cut := 0
+	for cut < len(f.kills) && now.Sub(f.kills[cut].Time) > f.window {
+		delete(f.seen, f.kills[cut].KillID)
+		cut++
+	}
+	f.kills = f.kills[cut:]
+}
+
+// Counts returns the kills in the window per system, in the same shape as ESI's system kills.
+func (f *KillFeed) Counts() []EsiSystemKills {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prune(time.Now())
+
+	bySystem := make(map[int]int) // system ID -> index into counts
+	var counts []EsiSystemKills
+	for _, km := range f.kills {
+		n, ok := bySystem[km.SolarSystemID]
+		if !ok {
+			n = len(counts)
+			bySystem[km.SolarSystemID] = n
+			counts = append(counts, EsiSystemKills{SystemID: km.SolarSystemID})
+		}
+		k := &counts[n]
+		switch {
+		case km.NPC:
+			k.NpcKills++
+		case capsuleTypeIDs[km.ShipTypeID]:
+			k.PodKills++
+		default:
+			k.ShipKills++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// redisQReplay serves recorded RedisQ responses in order, then empty packages.
+type redisQReplay struct {
+	mu        sync.Mutex
+	responses []string
+	killmails map[string]string // ESI killmail path -> body, for packages that only link
+}
+
+func (r *redisQReplay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if body, ok := r.killmails[req.URL.Path]; ok {
+		fmt.Fprint(w, body)
+		return
+	}
+	r.mu.Lock()
+	var body string
+	if len(r.responses) > 0 {
+		body, r.responses = r.responses[0], r.responses[1:]
+	}
+	r.mu.Unlock()
+	if body == "" {
+		time.Sleep(10 * time.Millisecond) // RedisQ holds the request while there is nothing to send
+		body = `{"package":null}`
+	}
+	fmt.Fprint(w, body)
+}
+
+// newReplayFeed starts a server replaying a few recorded killmails, an empty
+// package and a malformed body, and a feed reading from it. The server is
+// closed when the test ends.
+func newReplayFeed(t *testing.T) *KillFeed {
+	t.Helper()
+	now := time.Now().UTC()
+	killTime := func(ago time.Duration) string { return now.Add(-ago).Format(time.RFC3339) }
+
+	replay := &redisQReplay{
+		responses: []string{
+			fmt.Sprintf(`{"package":{"killID":1,"killmail":{"killmail_id":1,"killmail_time":%q,"solar_system_id":31000005,"victim":{"ship_type_id":587}},"zkb":{"npc":false}}}`, killTime(time.Minute)),
+			`{"package":null}`,
+			`{"package":{"killID":`,
+			`{"package":{"killID":2,"zkb":{"npc":false,"href":"%s/killmails/2/hash/"}}}`,
+			fmt.Sprintf(`{"package":{"killID":3,"killmail":{"killmail_id":3,"killmail_time":%q,"solar_system_id":31000005,"victim":{"ship_type_id":670}},"zkb":{"npc":false}}}`, killTime(2*time.Minute)),
+			fmt.Sprintf(`{"package":{"killID":4,"killmail":{"killmail_id":4,"killmail_time":%q,"solar_system_id":30000142,"victim":{"ship_type_id":587}},"zkb":{"npc":false}}}`, killTime(2*time.Hour)),
+		},
+		killmails: map[string]string{
+			"/killmails/2/hash/": fmt.Sprintf(`{"killmail_id":2,"killmail_time":%q,"solar_system_id":30002086,"victim":{"ship_type_id":587}}`, killTime(time.Minute)),
+		},
+	}
+	srv := httptest.NewServer(replay)
+	t.Cleanup(srv.Close)
+	replay.responses[3] = fmt.Sprintf(replay.responses[3], srv.URL)
+
+	feed, err := NewKillFeed(srv.URL+"/listen.php", "test", time.Hour)
+	if err != nil {
+		t.Fatalf("NewKillFeed: %v", err)
+	}
+	return feed
+}
+
+func TestKillFeedPoll(t *testing.T) {
+	feed := newReplayFeed(t)
+	ctx := context.Background()
+
+	steps := []struct {
+		name    string
+		killID  int // 0 when no kill is expected
+		wantErr bool
+	}{
+		{name: "inline killmail", killID: 1},
+		{name: "null package"},
+		{name: "malformed body", wantErr: true},
+		{name: "ESI link", killID: 2},
+	}
+	for _, step := range steps {
+		km, err := feed.poll(ctx)
+		if (err != nil) != step.wantErr {
+			t.Fatalf("%s: error %v, want error %v", step.name, err, step.wantErr)
+		}
+		switch {
+		case step.killID == 0 && km != nil:
+			t.Errorf("%s: got kill %d, want none", step.name, km.KillID)
+		case step.killID != 0 && (km == nil || km.KillID != step.killID):
+			t.Errorf("%s: got %+v, want kill %d", step.name, km, step.killID)
+		}
+	}
+}
+
+func TestKillFeedReplay(t *testing.T) {
+	feed := newReplayFeed(t)
+
+	var wg sync.WaitGroup
+	quit := make(chan struct{})
+	wg.Add(1)
+	go feed.Start(&wg, quit)
+
+	want := map[int]EsiSystemKills{
+		31000005: {SystemID: 31000005, ShipKills: 1, PodKills: 1},
+		30002086: {SystemID: 30002086, ShipKills: 1},
+	}
+	var got map[int]EsiSystemKills
+	// The malformed body puts the feed into a one second backoff.
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		got = make(map[int]EsiSystemKills)
+		for _, k := range feed.Counts() {
+			got[k.SystemID] = k
+		}
+		if len(got) == len(want) && got[31000005] == want[31000005] && got[30002086] == want[30002086] {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	close(quit)
+	wg.Wait()
+
+	if len(got) != len(want) {
+		t.Fatalf("got kills in %d systems, want %d: %+v", len(got), len(want), got)
+	}
+	for id, w := range want {
+		if got[id] != w {
+			t.Errorf("system %d: got %+v, want %+v", id, got[id], w)
+		}
+	}
+}